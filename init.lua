@@ -12,13 +12,542 @@ box.schema.space.create('polls', {
         {name = 'question', type = 'string'},
         {name = 'options', type = 'array'},
         {name= 'creator_id', type = 'string'},
-        {name = 'votes', type = 'array'},
-        {name = 'active', type = 'string'}
+        {name = 'status', type = 'string'},
+        {name = 'max_choices', type = 'unsigned'},
+        {name = 'kind', type = 'string'},
+        {name = 'channel_id', type = 'string'},
+        {name = 'expires_at', type = 'unsigned'},
+        {name = 'opens_at', type = 'unsigned'},
+        {name = 'history', type = 'array'},
+        {name = 'blind', type = 'boolean'},
+        {name = 'public', type = 'boolean'},
+        {name = 'quorum', type = 'unsigned'},
+        {name = 'threshold', type = 'unsigned'},
+        {name = 'tiebreak', type = 'string'},
+        {name = 'seed', type = 'unsigned'},
+        {name = 'tie_winner', type = 'string'},
+        {name = 'runoff_id', type = 'string'},
+        {name = 'writein', type = 'string'},
+        {name = 'suggestions', type = 'array'},
+        {name = 'option_ids', type = 'array'},
+        {name = 'next_option_id', type = 'unsigned'},
+        {name = 'short_id', type = 'string', is_nullable = true},
+        {name = 'team_id', type = 'string'},
+        {name = 'invited', type = 'array'},
+        {name = 'remind_before', type = 'unsigned'},
+        {name = 'reminded', type = 'boolean'},
+        {name = 'eligible_names', type = 'array'},
+        {name = 'eligible', type = 'array'},
+        {name = 'remind_at', type = 'unsigned'}
     }
 })
 
+box.schema.space.create('ballots', {
+    if_not_exists = true,
+    format = {
+        {name = 'poll_id', type = 'string'},
+        {name = 'user_id', type = 'string'},
+        {name = 'options', type = 'array'},
+        {name = 'cast_at', type = 'unsigned'},
+        {name = 'values', type = 'array'}
+    }
+})
+
+-- Первичные индексы создаются до миграций: шаги ниже заменяют кортежи
+-- голосований и вставляют бюллетени, а без индекса пространство не
+-- принимает записи. Остальные индексы ссылаются на поля, которые добавляют
+-- миграции, поэтому создаются после них.
 box.space.polls:create_index('primary', {
     parts = {'id'},
     if_not_exists = true
 })
 
+box.space.ballots:create_index('primary', {
+    parts = {'poll_id', 'user_id'},
+    if_not_exists = true
+})
+
+box.space.ballots:create_index('user', {
+    parts = {'user_id'},
+    unique = false,
+    if_not_exists = true
+})
+
+-- Пространства, созданные прежними версиями, сохраняют свой формат:
+-- if_not_exists не меняет существующее пространство. Шаги миграции ниже
+-- приводят такие пространства к текущему формату, каждый выполняется один раз.
+local function has_field(space, name)
+    for _, field in ipairs(space:format()) do
+        if field.name == name then
+            return true
+        end
+    end
+    return false
+end
+
+-- add_fields дописывает поля в конец формата пространства, а существующим
+-- кортежам — значения, которые возвращает fill.
+local function add_fields(space, fields, fill)
+    if has_field(space, fields[1].name) then
+        return
+    end
+    for _, tuple in ipairs(space:select()) do
+        local row = tuple:totable()
+        for _, value in ipairs(fill(row)) do
+            table.insert(row, value)
+        end
+        space:replace(row)
+    end
+    local format = space:format()
+    for _, field in ipairs(fields) do
+        table.insert(format, field)
+    end
+    space:format(format)
+end
+
+-- Счетчики голосов из поля votes переносятся в бюллетени без владельца,
+-- чтобы итоги старых голосований сохранились.
+box.once('polls_ballots', function()
+    local polls = box.space.polls
+    if not has_field(polls, 'votes') then
+        return
+    end
+    local format = polls:format()
+    polls:format({format[1], format[2], format[3], format[4]})
+    for _, tuple in ipairs(polls:select()) do
+        local row = tuple:totable()
+        for i, count in ipairs(row[5]) do
+            for n = 1, count do
+                box.space.ballots:insert({row[1], string.format('legacy-%d-%d', i, n), {row[3][i]}, 0, {}})
+            end
+        end
+        table.remove(row, 5)
+        polls:replace(row)
+    end
+    polls:format({format[1], format[2], format[3], format[4], format[6]})
+end)
+
+box.once('polls_max_choices', function()
+    add_fields(box.space.polls, {
+        {name = 'max_choices', type = 'unsigned'}
+    }, function()
+        return {1}
+    end)
+end)
+
+box.once('polls_kind', function()
+    add_fields(box.space.polls, {
+        {name = 'kind', type = 'string'}
+    }, function()
+        return {'plurality'}
+    end)
+end)
+
+box.once('ballots_values', function()
+    add_fields(box.space.ballots, {
+        {name = 'values', type = 'array'}
+    }, function()
+        return {{}}
+    end)
+end)
+
+box.once('polls_expires_at', function()
+    add_fields(box.space.polls, {
+        {name = 'channel_id', type = 'string'},
+        {name = 'expires_at', type = 'unsigned'}
+    }, function()
+        return {'', 0}
+    end)
+end)
+
+box.once('polls_status', function()
+    local polls = box.space.polls
+    if has_field(polls, 'active') then
+        local format = polls:format()
+        for _, field in ipairs(format) do
+            if field.name == 'active' then
+                field.name = 'status'
+            end
+        end
+        polls:format(format)
+    end
+    -- Прежние версии могли записать статус с заглавной буквы.
+    for _, tuple in ipairs(polls:select()) do
+        polls:update(tuple[1], {{'=', 5, string.lower(tuple[5])}})
+    end
+    add_fields(polls, {
+        {name = 'opens_at', type = 'unsigned'}
+    }, function()
+        return {0}
+    end)
+end)
+
+box.once('polls_history', function()
+    add_fields(box.space.polls, {
+        {name = 'history', type = 'array'}
+    }, function()
+        return {{}}
+    end)
+end)
+
+box.once('polls_blind', function()
+    add_fields(box.space.polls, {
+        {name = 'blind', type = 'boolean'}
+    }, function()
+        return {false}
+    end)
+end)
+
+box.once('polls_public', function()
+    add_fields(box.space.polls, {
+        {name = 'public', type = 'boolean'}
+    }, function()
+        return {false}
+    end)
+end)
+
+box.once('polls_quorum', function()
+    add_fields(box.space.polls, {
+        {name = 'quorum', type = 'unsigned'},
+        {name = 'threshold', type = 'unsigned'}
+    }, function()
+        return {0, 0}
+    end)
+end)
+
+box.once('polls_tiebreak', function()
+    add_fields(box.space.polls, {
+        {name = 'tiebreak', type = 'string'},
+        {name = 'seed', type = 'unsigned'},
+        {name = 'tie_winner', type = 'string'},
+        {name = 'runoff_id', type = 'string'}
+    }, function()
+        return {'', 0, '', ''}
+    end)
+end)
+
+box.once('polls_writein', function()
+    add_fields(box.space.polls, {
+        {name = 'writein', type = 'string'},
+        {name = 'suggestions', type = 'array'}
+    }, function()
+        return {'', {}}
+    end)
+end)
+
+box.once('polls_option_ids', function()
+    add_fields(box.space.polls, {
+        {name = 'option_ids', type = 'array'},
+        {name = 'next_option_id', type = 'unsigned'}
+    }, function(row)
+        local ids = {}
+        for i = 1, #row[3] do
+            ids[i] = i
+        end
+        return {ids, #row[3] + 1}
+    end)
+end)
+
+box.once('polls_short_id', function()
+    add_fields(box.space.polls, {
+        {name = 'short_id', type = 'string', is_nullable = true}
+    }, function()
+        return {box.NULL}
+    end)
+end)
+
+box.once('polls_team_id', function()
+    add_fields(box.space.polls, {
+        {name = 'team_id', type = 'string'}
+    }, function()
+        return {''}
+    end)
+end)
+
+box.once('polls_reminders', function()
+    add_fields(box.space.polls, {
+        {name = 'invited', type = 'array'},
+        {name = 'remind_before', type = 'unsigned'},
+        {name = 'reminded', type = 'boolean'}
+    }, function()
+        return {{}, 0, false}
+    end)
+end)
+
+box.once('polls_eligible', function()
+    add_fields(box.space.polls, {
+        {name = 'eligible_names', type = 'array'},
+        {name = 'eligible', type = 'array'}
+    }, function()
+        return {{}, {}}
+    end)
+end)
+
+-- remind_at хранит время автоматического напоминания: expires_at минус
+-- remind_before, пока напоминание не отправлено, иначе 0.
+box.once('polls_remind_at', function()
+    add_fields(box.space.polls, {
+        {name = 'remind_at', type = 'unsigned'}
+    }, function(row)
+        local expires_at, remind_before, reminded = row[9], row[27], row[28]
+        if remind_before == 0 or expires_at == 0 or reminded then
+            return {0}
+        end
+        return {math.max(expires_at - remind_before, 1)}
+    end)
+end)
+
+box.space.polls:create_index('short_id', {
+    parts = {{'short_id', 'string', is_nullable = true}},
+    unique = true,
+    if_not_exists = true
+})
+
+box.space.polls:create_index('creator', {
+    parts = {'creator_id'},
+    unique = false,
+    if_not_exists = true
+})
+
+box.space.polls:create_index('channel', {
+    parts = {'channel_id', 'status'},
+    unique = false,
+    if_not_exists = true
+})
+
+box.space.polls:create_index('expires', {
+    parts = {'status', 'expires_at'},
+    unique = false,
+    if_not_exists = true
+})
+
+box.space.polls:create_index('opens', {
+    parts = {'status', 'opens_at'},
+    unique = false,
+    if_not_exists = true
+})
+
+box.space.polls:create_index('remind', {
+    parts = {'status', 'remind_at'},
+    unique = false,
+    if_not_exists = true
+})
+
+box.schema.space.create('weights', {
+    if_not_exists = true,
+    format = {
+        {name = 'scope', type = 'string'},
+        {name = 'user_id', type = 'string'},
+        {name = 'weight', type = 'unsigned'}
+    }
+})
+
+box.space.weights:create_index('primary', {
+    parts = {'scope', 'user_id'},
+    if_not_exists = true
+})
+
+box.schema.space.create('templates', {
+    if_not_exists = true,
+    format = {
+        {name = 'owner_id', type = 'string'},
+        {name = 'name', type = 'string'},
+        {name = 'options', type = 'array'},
+        {name = 'kind', type = 'string'},
+        {name = 'max_choices', type = 'unsigned'},
+        {name = 'blind', type = 'boolean'},
+        {name = 'public', type = 'boolean'},
+        {name = 'quorum', type = 'unsigned'},
+        {name = 'threshold', type = 'unsigned'},
+        {name = 'tiebreak', type = 'string'},
+        {name = 'writein', type = 'string'}
+    }
+})
+
+box.space.templates:create_index('primary', {
+    parts = {'owner_id', 'name'},
+    if_not_exists = true
+})
+
+box.schema.space.create('search_terms', {
+    if_not_exists = true,
+    format = {
+        {name = 'term', type = 'string'},
+        {name = 'poll_id', type = 'string'},
+        {name = 'channel_id', type = 'string'},
+        {name = 'team_id', type = 'string'},
+        {name = 'weight', type = 'unsigned'}
+    }
+})
+
+box.space.search_terms:create_index('primary', {
+    parts = {'term', 'poll_id'},
+    if_not_exists = true
+})
+
+box.space.search_terms:create_index('poll', {
+    parts = {'poll_id'},
+    unique = false,
+    if_not_exists = true
+})
+
+box.space.search_terms:create_index('channel', {
+    parts = {'channel_id', 'term', 'poll_id'},
+    if_not_exists = true
+})
+
+box.space.search_terms:create_index('team', {
+    parts = {'team_id', 'term', 'poll_id'},
+    if_not_exists = true
+})
+
+-- search_terms разбивает текст на слова так же, как searchTerms в
+-- repository/searchrepository.go.
+local function search_terms(text)
+    local terms, seen, word = {}, {}, {}
+    local function flush()
+        if #word >= 2 then
+            local term = table.concat(word)
+            if not seen[term] then
+                seen[term] = true
+                table.insert(terms, term)
+            end
+        end
+        word = {}
+    end
+    text = utf8.lower(text)
+    local pos = 1
+    while true do
+        local next_pos, code = utf8.next(text, pos)
+        if next_pos == nil then
+            break
+        end
+        if utf8.isalpha(code) or utf8.isdigit(code) then
+            table.insert(word, utf8.char(code))
+        else
+            flush()
+        end
+        pos = next_pos
+    end
+    flush()
+    return terms
+end
+
+-- Голосования, созданные до поискового индекса, индексируются один раз с
+-- теми же весами, что и в indexPoll: слово вопроса весит 2, слово варианта 1.
+box.once('search_terms_backfill', function()
+    for _, poll in box.space.polls:pairs() do
+        if box.space.search_terms.index.poll:count(poll.id) == 0 then
+            local weights = {}
+            for _, term in ipairs(search_terms(poll.question)) do
+                weights[term] = (weights[term] or 0) + 2
+            end
+            for _, option in ipairs(poll.options) do
+                for _, term in ipairs(search_terms(option)) do
+                    weights[term] = (weights[term] or 0) + 1
+                end
+            end
+            for term, weight in pairs(weights) do
+                box.space.search_terms:replace({term, poll.id, poll.channel_id, poll.team_id, weight})
+            end
+        end
+    end
+end)
+
+
+local function same_array(a, b)
+    if #a ~= #b then
+        return false
+    end
+    for i = 1, #a do
+        if a[i] ~= b[i] then
+            return false
+        end
+    end
+    return true
+end
+
+-- update_poll_options заменяет варианты ответа голосования, только если с
+-- момента чтения их никто не изменил, и возвращает false в противном случае.
+function update_poll_options(poll_id, old_options, old_ids, options, ids, next_id, max_choices)
+    local poll = box.space.polls:get(poll_id)
+    if poll == nil or not same_array(poll.options, old_options) or not same_array(poll.option_ids, old_ids) then
+        return false
+    end
+    box.space.polls:update(poll_id, {
+        {'=', 'options', options},
+        {'=', 'option_ids', ids},
+        {'=', 'next_option_id', next_id},
+        {'=', 'max_choices', max_choices}
+    })
+    return true
+end
+
+box.schema.func.create('update_poll_options', {if_not_exists = true})
+box.schema.user.grant('voter', 'execute', 'function', 'update_poll_options', {if_not_exists = true})
+
+-- suggest_poll_options дописывает в очередь предложений варианты, которых в
+-- ней еще нет. Процедура не уступает управление между чтением и записью,
+-- поэтому одновременные предложения не затирают друг друга.
+function suggest_poll_options(poll_id, options)
+    local poll = box.space.polls:get(poll_id)
+    if poll == nil then
+        return false
+    end
+    local suggestions = poll.suggestions
+    for _, option in ipairs(options) do
+        local found = false
+        for _, suggestion in ipairs(suggestions) do
+            if suggestion == option then
+                found = true
+            end
+        end
+        if not found then
+            table.insert(suggestions, option)
+        end
+    end
+    box.space.polls:update(poll_id, {{'=', 'suggestions', suggestions}})
+    return true
+end
+
+box.schema.func.create('suggest_poll_options', {if_not_exists = true})
+box.schema.user.grant('voter', 'execute', 'function', 'suggest_poll_options', {if_not_exists = true})
+
+-- remove_poll_suggestion убирает вариант из очереди предложений и
+-- возвращает false, если его там уже нет.
+function remove_poll_suggestion(poll_id, option)
+    local poll = box.space.polls:get(poll_id)
+    if poll == nil then
+        return false
+    end
+    local suggestions = {}
+    local found = false
+    for _, suggestion in ipairs(poll.suggestions) do
+        if suggestion == option then
+            found = true
+        else
+            table.insert(suggestions, suggestion)
+        end
+    end
+    if found then
+        box.space.polls:update(poll_id, {{'=', 'suggestions', suggestions}})
+    end
+    return found
+end
+
+box.schema.func.create('remove_poll_suggestion', {if_not_exists = true})
+box.schema.user.grant('voter', 'execute', 'function', 'remove_poll_suggestion', {if_not_exists = true})
+
+-- set_poll_runoff записывает второй тур, только если поле runoff_id все еще
+-- равно expected, и возвращает false в противном случае.
+function set_poll_runoff(poll_id, expected, runoff_id)
+    local poll = box.space.polls:get(poll_id)
+    if poll == nil or poll.runoff_id ~= expected then
+        return false
+    end
+    box.space.polls:update(poll_id, {{'=', 'runoff_id', runoff_id}})
+    return true
+end
+
+box.schema.func.create('set_poll_runoff', {if_not_exists = true})
+box.schema.user.grant('voter', 'execute', 'function', 'set_poll_runoff', {if_not_exists = true})
@@ -1,6 +1,10 @@
 package api
 
 import (
+	"errors"
+	"net/http"
+
+	"github.com/bllooop/votingbot/internal/domain"
 	logger "github.com/bllooop/votingbot/pkg/logging"
 	"github.com/gin-gonic/gin"
 )
@@ -13,3 +17,18 @@ func newErrorResponse(c *gin.Context, statusCode int, message string) {
 	logger.Log.Error().Msg(message)
 	c.AbortWithStatusJSON(statusCode, errorResponse{message})
 }
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrAlreadyVoted):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrNotVoted):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrIneligible):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
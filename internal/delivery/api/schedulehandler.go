@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	logger "github.com/bllooop/votingbot/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// exportCalendar выдает лучший слот закрытого голосования типа
+// domain.KindSchedule в формате iCalendar.
+func (h *Handler) exportCalendar(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования")
+		return
+	}
+	pollID := args[0]
+	logger.Log.Info().Msgf("Получен запрос на экспорт в календарь голосования %s", pollID)
+	results, err := h.Usecases.Polls.GetRes(pollID, req.UserID, false)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	if results.Kind != domain.KindSchedule {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: экспорт в календарь доступен только для голосования типа schedule")
+		return
+	}
+	if results.Status != domain.StatusClosed && results.Status != domain.StatusArchived {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: лучший слот определяется после закрытия голосования")
+		return
+	}
+	if results.Winner == "" {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: лучший слот не определен")
+		return
+	}
+	start, end, err := domain.ParseSlot(results.Winner, time.Local)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text: fmt.Sprintf("Сохраните текст ниже в файл %s.ics:\n```\n%s```",
+			displayID(results), formatCalendar(results, start, end, time.Now())),
+	})
+}
+
+// formatSlots выводит отметки участников по слотам в виде markdown-таблицы.
+func formatSlots(slots []domain.SlotResult) string {
+	text := "| Слот | Да | Возможно | Нет |\n|---|---|---|---|\n"
+	for i, slot := range slots {
+		text += fmt.Sprintf("| %d. %s | %d | %d | %d |\n", i+1, slot.Option, slot.Yes, slot.Maybe, slot.No)
+	}
+	return text
+}
+
+func formatCalendar(results domain.PollResults, start, end, now time.Time) string {
+	const layout = "20060102T150405Z"
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//votingbot//RU",
+		"BEGIN:VEVENT",
+		"UID:" + results.PollID + "@votingbot",
+		"DTSTAMP:" + now.UTC().Format(layout),
+		"DTSTART:" + start.UTC().Format(layout),
+		"DTEND:" + end.UTC().Format(layout),
+		"SUMMARY:" + escapeCalendarText(results.Question),
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func escapeCalendarText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
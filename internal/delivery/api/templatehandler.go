@@ -0,0 +1,139 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	logger "github.com/bllooop/votingbot/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) templateCommand(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите действие save, list или delete")
+		return
+	}
+
+	switch args[0] {
+	case "save":
+		h.saveTemplate(c, req, args[1:])
+	case "delete":
+		h.deleteTemplate(c, req, args[1:])
+	case "list":
+		h.listTemplates(c, req)
+	default:
+		c.JSON(http.StatusOK, gin.H{"response_type": "ephemeral", "text": "Неизвестное действие с шаблонами"})
+	}
+}
+
+func (h *Handler) saveTemplate(c *gin.Context, req domain.MattermostRequest, args []string) {
+	flags, args := parseFlags(args, "blind", "public")
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите название шаблона и варианты ответа")
+		return
+	}
+	poll := domain.Poll{MaxChoices: 1}
+	if err := parseSettings(&poll, flags); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+		return
+	}
+	template := domain.Template{
+		Name:       args[0],
+		OwnerID:    req.UserID,
+		Options:    args[1:],
+		Kind:       poll.Kind,
+		MaxChoices: poll.MaxChoices,
+		Blind:      poll.Blind,
+		Public:     poll.Public,
+		Quorum:     poll.Quorum,
+		Threshold:  poll.Threshold,
+		TieBreak:   poll.TieBreak,
+		WriteIn:    poll.WriteIn,
+	}
+	logger.Log.Info().Msgf("Получен запрос на сохранение шаблона %s с вариантами %s", template.Name, template.Options)
+	err := h.Usecases.Templates.SaveTemplateDB(template)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         "Шаблон сохранен: " + formatTemplate(template),
+	})
+}
+
+func (h *Handler) deleteTemplate(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите название шаблона")
+		return
+	}
+	logger.Log.Info().Msgf("Получен запрос на удаление шаблона %s", args[0])
+	err := h.Usecases.Templates.DeleteTemplateDB(req.UserID, args[0])
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         fmt.Sprintf("Шаблон %s удален", args[0]),
+	})
+}
+
+func (h *Handler) listTemplates(c *gin.Context, req domain.MattermostRequest) {
+	logger.Log.Info().Msgf("Получен запрос на список шаблонов пользователя %s", req.UserID)
+	templates, err := h.Usecases.Templates.ListTemplatesDB(req.UserID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	responseText := "Шаблоны голосований:\n"
+	for _, template := range templates {
+		responseText += formatTemplate(template) + "\n"
+	}
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         responseText,
+	})
+}
+
+func formatTemplate(template domain.Template) string {
+	text := template.Name
+	if template.OwnerID == "" {
+		text += " (встроенный)"
+	}
+	text += ": " + strings.Join(template.Options, ", ")
+	var settings []string
+	if template.Kind != "" && template.Kind != domain.KindPlurality {
+		settings = append(settings, "--kind "+template.Kind)
+	}
+	if template.MaxChoices > 1 {
+		settings = append(settings, fmt.Sprintf("--multi %d", template.MaxChoices))
+	}
+	if template.Blind {
+		settings = append(settings, "--blind")
+	}
+	if template.Public {
+		settings = append(settings, "--public")
+	}
+	if template.Quorum > 0 {
+		settings = append(settings, fmt.Sprintf("--quorum %d", template.Quorum))
+	}
+	if template.Threshold > 0 {
+		settings = append(settings, fmt.Sprintf("--threshold %d%%", template.Threshold))
+	}
+	if template.TieBreak != "" {
+		settings = append(settings, "--tiebreak "+template.TieBreak)
+	}
+	if template.WriteIn != "" {
+		settings = append(settings, "--writein "+template.WriteIn)
+	}
+	if len(settings) > 0 {
+		text += " [" + strings.Join(settings, " ") + "]"
+	}
+	return text
+}
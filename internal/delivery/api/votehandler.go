@@ -1,9 +1,14 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bllooop/votingbot/internal/domain"
 	logger "github.com/bllooop/votingbot/pkg/logging"
@@ -36,8 +41,40 @@ func (h *Handler) VoteHandler(c *gin.Context) {
 		h.createPoll(c, req, args[1:])
 	case "cast":
 		h.castVote(c, req, args[1:])
+	case "revote":
+		h.revote(c, req, args[1:])
+	case "retract":
+		h.retractVote(c, req, args[1:])
 	case "results":
 		h.getResults(c, req, args[1:])
+	case "publish":
+		h.publishPoll(c, req, args[1:])
+	case "reopen":
+		h.reopenPoll(c, req, args[1:])
+	case "list":
+		h.listPolls(c, req, args[1:])
+	case "remind":
+		h.remindVoters(c, req, args[1:])
+	case "template":
+		h.templateCommand(c, req, args[1:])
+	case "mine":
+		h.minePolls(c, req, args[1:])
+	case "search":
+		h.searchPolls(c, req, args[1:])
+	case "edit":
+		h.editPoll(c, req, args[1:])
+	case "approve":
+		h.approveOption(c, req, args[1:])
+	case "tiebreak":
+		h.tieBreak(c, req, args[1:])
+	case "archive":
+		h.archivePoll(c, req, args[1:])
+	case "voters":
+		h.getVoters(c, req, args[1:])
+	case "weight":
+		h.weightCommand(c, req, args[1:])
+	case "ics":
+		h.exportCalendar(c, req, args[1:])
 	case "close":
 		h.closePoll(c, req, args[1:])
 	case "delete":
@@ -49,21 +86,94 @@ func (h *Handler) VoteHandler(c *gin.Context) {
 }
 
 func (h *Handler) createPoll(c *gin.Context, req domain.MattermostRequest, args []string) {
-	if len(args) < 2 {
+	invited, args, err := cutMentions(args, "voters")
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+		return
+	}
+	eligible, args, err := cutMentions(args, "allow")
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+		return
+	}
+	flags, args := parseFlags(args, "draft", "blind", "public", "no-blind", "no-public")
+	templateName, withTemplate := flags["template"]
+	_, withSlots := flags["slots"]
+	if len(args) < 1 || len(args) < 2 && !withTemplate && !withSlots {
 		newErrorResponse(c, http.StatusBadRequest, "Ошибка: нужно указать вопрос и хотя бы два варианта ответа")
 		return
 	}
 
-	question := args[0]
-	options := args[1:]
-	logger.Log.Info().Msgf("Получен запрос на создание голосования с данными %s, %s", question, options)
-	pollID, options, err := h.Usecases.Polls.CreateDB(question, options, req.UserID)
+	poll := domain.Poll{
+		Question:      args[0],
+		Options:       args[1:],
+		CreatorID:     req.UserID,
+		ChannelID:     req.ChannelID,
+		TeamID:        req.TeamID,
+		Invited:       invited,
+		EligibleNames: eligible,
+	}
+	var template *domain.Template
+	if withTemplate {
+		found, err := h.Usecases.Templates.GetTemplateDB(req.UserID, templateName)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("")
+			newErrorResponse(c, errorStatus(err), err.Error())
+			return
+		}
+		template = &found
+	}
+	poll, err = createSettings(poll, template, flags)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+		return
+	}
+	if value, ok := flags["expires"]; ok {
+		expiresAt, err := parseDeadline(value, time.Now())
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+			return
+		}
+		poll.ExpiresAt = expiresAt.Unix()
+	}
+	if value, ok := flags["remind"]; ok {
+		remindBefore, err := time.ParseDuration(value)
+		if err != nil || remindBefore <= 0 {
+			newErrorResponse(c, http.StatusBadRequest, "Ошибка: после --remind нужно указать длительность, например 1h")
+			return
+		}
+		poll.RemindBefore = int64(remindBefore.Seconds())
+	}
+	if value, ok := flags["opens"]; ok {
+		opensAt, err := parseDeadline(value, time.Now())
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+			return
+		}
+		poll.OpensAt = opensAt.Unix()
+	}
+	if _, ok := flags["draft"]; ok {
+		poll.Status = domain.StatusDraft
+	}
+	logger.Log.Info().Msgf("Получен запрос на создание голосования с данными %s, %s", poll.Question, poll.Options)
+	pollID, options, err := h.Usecases.Polls.CreateDB(poll)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("")
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	responseText := fmt.Sprintf("Голосование создано! ID: %s, Варианты ответов: %s", pollID, options)
+	responseText := fmt.Sprintf("Голосование создано! ID: %s, Варианты ответов: %s", pollID, FormatOptions(options))
+	if poll.MaxChoices > 1 {
+		responseText += fmt.Sprintf(", можно выбрать до %d вариантов", poll.MaxChoices)
+	}
+	if poll.ExpiresAt > 0 {
+		responseText += fmt.Sprintf(", голосование завершится %s", formatTime(poll.ExpiresAt))
+	}
+	if poll.Status == domain.StatusDraft {
+		responseText += ". Голосование сохранено как черновик, для открытия выполните publish " + pollID
+	} else if poll.OpensAt > time.Now().Unix() {
+		responseText += fmt.Sprintf(". Голосование откроется %s", formatTime(poll.OpensAt))
+	}
 	c.JSON(http.StatusOK, domain.MattermostResponse{
 		ResponseType: "in_channel",
 		Text:         responseText,
@@ -76,43 +186,387 @@ func (h *Handler) castVote(c *gin.Context, req domain.MattermostRequest, args []
 		return
 	}
 	pollID := args[0]
-	option := args[1]
-	logger.Log.Info().Msgf("Получен запрос на выбор варианта %s в голосовании %s", option, pollID)
-	err := h.Usecases.Polls.CastDB(pollID, option)
+	options, values, err := parseBallotArgs(args[1:])
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+		return
+	}
+	logger.Log.Info().Msgf("Получен запрос на выбор вариантов %s в голосовании %s", options, pollID)
+	options, err = h.Usecases.Polls.CastDB(domain.Ballot{PollID: pollID, UserID: req.UserID, Options: options, Values: values})
 	if err != nil {
+		if respondSuggested(c, err) {
+			return
+		}
 		logger.Log.Error().Err(err).Msg("")
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newErrorResponse(c, errorStatus(err), err.Error())
 		return
 	}
-	responseText := fmt.Sprintf("%s проголосовал за %s в голосовании %s", req.UserID, option, pollID)
+	responseText := fmt.Sprintf("Ваш голос за %s в голосовании %s учтен", strings.Join(options, ", "), pollID)
 	c.JSON(http.StatusOK, domain.MattermostResponse{
-		ResponseType: "in_channel",
+		ResponseType: "ephemeral",
+		Text:         responseText,
+	})
+}
+
+func (h *Handler) revote(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 2 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования и новый вариант ответа")
+		return
+	}
+	pollID := args[0]
+	options, values, err := parseBallotArgs(args[1:])
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+		return
+	}
+	logger.Log.Info().Msgf("Получен запрос на изменение голоса на варианты %s в голосовании %s", options, pollID)
+	options, err = h.Usecases.Polls.RevoteDB(domain.Ballot{PollID: pollID, UserID: req.UserID, Options: options, Values: values})
+	if err != nil {
+		if respondSuggested(c, err) {
+			return
+		}
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Ваш голос в голосовании %s изменен на %s", pollID, strings.Join(options, ", "))
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         responseText,
+	})
+}
+
+// respondSuggested отвечает участнику, что предложенные им варианты ушли
+// создателю на одобрение. Это не ошибка: ответ видит только участник.
+func respondSuggested(c *gin.Context, err error) bool {
+	if !errors.Is(err, domain.ErrSuggested) {
+		return false
+	}
+	logger.Log.Info().Msg(err.Error())
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         err.Error(),
+	})
+	return true
+}
+
+func (h *Handler) retractVote(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования")
+		return
+	}
+	pollID := args[0]
+	logger.Log.Info().Msgf("Получен запрос на отзыв голоса в голосовании %s", pollID)
+	err := h.Usecases.Polls.RetractDB(pollID, req.UserID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Ваш голос в голосовании %s отозван", pollID)
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
 		Text:         responseText,
 	})
 }
 
 func (h *Handler) getResults(c *gin.Context, req domain.MattermostRequest, args []string) {
+	flags, args := parseFlags(args, "peek")
 	if len(args) < 1 {
 		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования")
 		return
 	}
 	pollID := args[0]
+	_, peek := flags["peek"]
 	logger.Log.Info().Msgf("Получен запрос на данные о голосовании %s", pollID)
-	results, err := h.Usecases.Polls.GetRes(pollID)
+	results, err := h.Usecases.Polls.GetRes(pollID, req.UserID, peek)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("")
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newErrorResponse(c, errorStatus(err), err.Error())
 		return
 	}
-	var resultText, responseText string
-	if len(results) == 0 {
-		responseText = fmt.Sprintf("Результаты голосования %s: нет данных", pollID)
-	} else {
-		question := results[0].Question
-		for _, res := range results {
-			resultText += fmt.Sprintf("%s: %d голосов\n", res.Option, res.Count)
+	responseType := "in_channel"
+	if peek {
+		responseType = "ephemeral"
+	}
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: responseType,
+		Text:         FormatResults(results),
+	})
+}
+
+func FormatResults(results domain.PollResults) string {
+	if results.Hidden {
+		return fmt.Sprintf("Голосование %s, %s: результаты скрыты до закрытия голосования\n%s%s",
+			displayID(results), results.Question, formatTurnout(results), formatStatus(results))
+	}
+	if len(results.Options) == 0 {
+		return fmt.Sprintf("Результаты голосования %s: нет данных", displayID(results))
+	}
+	var resultText string
+	if domain.IsRanked(results.Kind) {
+		resultText += "Первые предпочтения:\n"
+	}
+	if results.Kind == domain.KindSchedule {
+		resultText += formatSlots(results.Slots)
+	}
+	for i, res := range results.Options {
+		switch {
+		case results.Kind == domain.KindSchedule:
+		case results.Weighted:
+			resultText += fmt.Sprintf("%d. %s: %d голосов, с учетом весов %d\n", i+1, res.Option, res.Count, res.Weighted)
+		default:
+			resultText += fmt.Sprintf("%d. %s: %d голосов\n", i+1, res.Option, res.Count)
+		}
+	}
+	resultText += formatTurnout(results)
+	resultText += formatStatus(results)
+	if len(results.History) > 1 {
+		resultText += formatHistory(results.History)
+	}
+	switch results.Kind {
+	case domain.KindRanked:
+		resultText += formatRounds(results.Rounds)
+	case domain.KindSchulze:
+		resultText += formatMatrix("Попарные предпочтения (строка против столбца):", results.Options, results.Pairwise)
+		resultText += formatMatrix("Сильнейшие пути:", results.Options, results.Paths)
+	case domain.KindScore:
+		resultText += formatScores(results.Scores)
+	}
+	resultText += formatWinner(results)
+	if results.Kind == domain.KindSchedule && results.Winner != "" &&
+		(results.Status == domain.StatusClosed || results.Status == domain.StatusArchived) {
+		resultText += fmt.Sprintf("Календарь с лучшим слотом: ics %s\n", displayID(results))
+	}
+	if len(results.Suggested) > 0 {
+		resultText += fmt.Sprintf("Предложенные варианты, ожидают одобрения: %s\n", strings.Join(results.Suggested, ", "))
+	}
+	if results.Decision != nil {
+		resultText += formatDecision(results.Decision, results.Voters, results.Status)
+	}
+	return fmt.Sprintf("Результаты голосования  %s, %s:\n%s", displayID(results), results.Question, resultText)
+}
+
+func formatTurnout(results domain.PollResults) string {
+	if results.Electorate == 0 {
+		return fmt.Sprintf("Проголосовало участников: %d\n", results.Voters)
+	}
+	return fmt.Sprintf("Проголосовало участников: %d из %d допущенных (%.1f%%)\n",
+		results.Voters, results.Electorate, float64(results.Voters)*100/float64(results.Electorate))
+}
+
+func displayID(results domain.PollResults) string {
+	if results.ShortID != "" {
+		return results.ShortID
+	}
+	return results.PollID
+}
+
+// FormatOptions нумерует варианты ответа: номер можно указать в cast вместо
+// текста варианта.
+func FormatOptions(options []string) string {
+	numbered := make([]string, len(options))
+	for i, option := range options {
+		numbered[i] = fmt.Sprintf("%d. %s", i+1, option)
+	}
+	return strings.Join(numbered, "; ")
+}
+
+func formatDecision(decision *domain.Decision, voters int, status string) string {
+	var text string
+	if decision.Quorum > 0 {
+		text += fmt.Sprintf("Кворум: %d из %d", voters, decision.Quorum)
+		if decision.QuorumMet {
+			text += ", достигнут\n"
+		} else {
+			text += ", не достигнут\n"
+		}
+	}
+	if decision.Threshold > 0 {
+		text += fmt.Sprintf("Порог принятия решения: %d%%\n", decision.Threshold)
+	}
+	if decision.Leader != "" {
+		text += fmt.Sprintf("Лидер: %s", decision.Leader)
+		if decision.Share > 0 {
+			text += fmt.Sprintf(" (%.1f%%)", decision.Share)
 		}
-		responseText = fmt.Sprintf("Результаты голосования  %s, %s:\n%s", pollID, question, resultText)
+		text += "\n"
+	}
+	if status != domain.StatusClosed && status != domain.StatusArchived {
+		return text
+	}
+	switch {
+	case !decision.QuorumMet:
+		text += "Итог: решение не принято, кворум не достигнут\n"
+	case decision.Passed:
+		text += fmt.Sprintf("Итог: принят вариант %s\n", decision.Leader)
+	case decision.Leader == "":
+		text += "Итог: решение не принято, лидер не определен\n"
+	default:
+		text += "Итог: решение не принято, лидер не набрал порог\n"
+	}
+	return text
+}
+
+func formatBallot(poll domain.Poll, ballot domain.Ballot) string {
+	switch {
+	case domain.IsRanked(poll.Kind):
+		return strings.Join(ballot.Options, " > ")
+	case poll.Kind == domain.KindScore:
+		scored := make([]string, 0, len(ballot.Options))
+		for i, option := range ballot.Options {
+			if i < len(ballot.Values) {
+				scored = append(scored, fmt.Sprintf("%s=%d", option, ballot.Values[i]))
+			}
+		}
+		return strings.Join(scored, ", ")
+	case poll.Kind == domain.KindSchedule:
+		marked := make([]string, 0, len(ballot.Options))
+		for i, option := range ballot.Options {
+			if i < len(ballot.Values) {
+				marked = append(marked, fmt.Sprintf("%s — %s", option, domain.AvailabilityTitle(ballot.Values[i])))
+			}
+		}
+		return strings.Join(marked, ", ")
+	default:
+		return strings.Join(ballot.Options, ", ")
+	}
+}
+
+func formatStatus(results domain.PollResults) string {
+	if results.Status != domain.StatusActive {
+		return fmt.Sprintf("Статус голосования: %s\n", domain.StatusTitle(results.Status))
+	}
+	if results.ExpiresAt != "" {
+		return fmt.Sprintf("Голосование завершится: %s\n", results.ExpiresAt)
+	}
+	return ""
+}
+
+func formatHistory(history []domain.StatusChange) string {
+	text := "История:\n"
+	for _, change := range history {
+		text += fmt.Sprintf("%s — %s", formatTime(change.At), domain.StatusTitle(change.Status))
+		if change.UserID != "" {
+			text += fmt.Sprintf(" (%s)", change.UserID)
+		}
+		text += "\n"
+	}
+	return text
+}
+
+func formatScores(scores []domain.ScoreResult) string {
+	var text string
+	for _, score := range scores {
+		histogram := make([]string, 0, len(score.Histogram))
+		for i, count := range score.Histogram {
+			histogram = append(histogram, fmt.Sprintf("%d: %d", domain.MinScore+i, count))
+		}
+		text += fmt.Sprintf("%s: среднее %.2f, медиана %.1f, оценок %d, распределение [%s]\n",
+			score.Option, score.Mean, score.Median, score.Count, strings.Join(histogram, ", "))
+	}
+	return text
+}
+
+func formatRounds(rounds []domain.Round) string {
+	var text string
+	for _, round := range rounds {
+		counts := make([]string, 0, len(round.Counts))
+		for _, res := range round.Counts {
+			counts = append(counts, fmt.Sprintf("%s — %d", res.Option, res.Count))
+		}
+		text += fmt.Sprintf("Раунд %d: %s", round.Number, strings.Join(counts, ", "))
+		if len(round.Eliminated) > 0 {
+			text += fmt.Sprintf("; выбывает %s", strings.Join(round.Eliminated, ", "))
+		}
+		text += "\n"
+	}
+	return text
+}
+
+// formatMatrix выводит квадратную матрицу по вариантам ответа в виде
+// markdown-таблицы, которую Mattermost отображает как таблицу.
+func formatMatrix(title string, options domain.Results, matrix [][]int) string {
+	if len(matrix) != len(options) {
+		return ""
+	}
+	text := title + "\n|  |"
+	for i := range options {
+		text += fmt.Sprintf(" %d |", i+1)
+	}
+	text += "\n|---|" + strings.Repeat("---|", len(options)) + "\n"
+	for i, res := range options {
+		text += fmt.Sprintf("| %d. %s |", i+1, res.Option)
+		for j := range options {
+			if i == j {
+				text += " — |"
+			} else {
+				text += fmt.Sprintf(" %d |", matrix[i][j])
+			}
+		}
+		text += "\n"
+	}
+	return text
+}
+
+func formatWinner(results domain.PollResults) string {
+	closed := results.Status == domain.StatusClosed || results.Status == domain.StatusArchived
+	var text string
+	if len(results.Tied) > 1 {
+		text += fmt.Sprintf("Ничья между вариантами: %s\n", strings.Join(results.Tied, ", "))
+	}
+	switch {
+	case results.Winner != "" && !closed:
+		return text + fmt.Sprintf("Лидирует: %s\n", results.Winner)
+	case results.Winner != "" && len(results.Tied) > 1:
+		text += fmt.Sprintf("Победитель: %s (%s)\n", results.Winner, tieBreakTitle(results))
+	case results.Winner != "":
+		text += fmt.Sprintf("Победитель: %s\n", results.Winner)
+	case !closed:
+	case results.RunoffID == domain.RunoffPending:
+		text += "Второй тур создается\n"
+	case results.RunoffID != "":
+		text += fmt.Sprintf("Назначен второй тур: %s\n", results.RunoffID)
+	case len(results.Tied) > 1 && results.TieBreak == domain.TieBreakCreator:
+		text += fmt.Sprintf("Победителя выберет создатель командой tiebreak %s \"{вариант}\"\n", displayID(results))
+	default:
+		text += "Победитель не определен\n"
+	}
+	return text
+}
+
+func tieBreakTitle(results domain.PollResults) string {
+	switch results.TieBreak {
+	case domain.TieBreakCreator:
+		return "выбор создателя"
+	case domain.TieBreakRandom:
+		return fmt.Sprintf("случайный выбор, зерно %d", results.Seed)
+	case domain.TieBreakEarliest:
+		return "раньше набрал итоговый результат"
+	default:
+		return results.TieBreak
+	}
+}
+
+func (h *Handler) getVoters(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования")
+		return
+	}
+	pollID := args[0]
+	logger.Log.Info().Msgf("Получен запрос на список участников голосования %s", pollID)
+	poll, ballots, err := h.Usecases.Polls.VotersDB(pollID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Участники голосования %s, %s:\n", pollID, poll.Question)
+	if len(ballots) == 0 {
+		responseText += "пока никто не проголосовал"
+	}
+	for _, ballot := range ballots {
+		responseText += fmt.Sprintf("%s: %s\n", ballot.UserID, formatBallot(poll, ballot))
 	}
 
 	c.JSON(http.StatusOK, domain.MattermostResponse{
@@ -131,10 +585,319 @@ func (h *Handler) closePoll(c *gin.Context, req domain.MattermostRequest, args [
 	err := h.Usecases.Polls.CloseDB(pollID, req.UserID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("")
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newErrorResponse(c, errorStatus(err), err.Error())
 		return
 	}
 	responseText := fmt.Sprintf("Голосование %s закрыто", pollID)
+	results, err := h.Usecases.Polls.GetRes(pollID, req.UserID, false)
+	if err != nil {
+		logger.Log.Error().Err(err).Msgf("Не удалось получить итоги голосования %s", pollID)
+	} else {
+		responseText += "\n" + FormatResults(results)
+	}
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "in_channel",
+		Text:         responseText,
+	})
+}
+
+func (h *Handler) publishPoll(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования")
+		return
+	}
+	pollID := args[0]
+	logger.Log.Info().Msgf("Получен запрос на публикацию голосования %s", pollID)
+	poll, err := h.Usecases.Polls.PublishDB(pollID, req.UserID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Голосование %s опубликовано: %s", pollID, poll.Question)
+	if poll.Status == domain.StatusScheduled {
+		responseText += fmt.Sprintf(". Голосование откроется %s", formatTime(poll.OpensAt))
+	}
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "in_channel",
+		Text:         responseText,
+	})
+}
+
+func (h *Handler) reopenPoll(c *gin.Context, req domain.MattermostRequest, args []string) {
+	flags, args := parseFlags(args)
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования")
+		return
+	}
+	pollID := args[0]
+	var expiresAt int64
+	if value, ok := flags["expires"]; ok {
+		deadline, err := parseDeadline(value, time.Now())
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+			return
+		}
+		expiresAt = deadline.Unix()
+	}
+	logger.Log.Info().Msgf("Получен запрос на повторное открытие голосования %s", pollID)
+	poll, err := h.Usecases.Polls.ReopenDB(pollID, req.UserID, expiresAt)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Голосование %s снова открыто", pollID)
+	if poll.ExpiresAt > 0 {
+		responseText += fmt.Sprintf(", голосование завершится %s", formatTime(poll.ExpiresAt))
+	}
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "in_channel",
+		Text:         responseText,
+	})
+}
+
+func (h *Handler) listPolls(c *gin.Context, req domain.MattermostRequest, args []string) {
+	flags, args := parseFlags(args)
+	filter := domain.ListActive
+	if len(args) > 0 {
+		filter = args[0]
+	}
+	page, err := parsePage(flags)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+		return
+	}
+	logger.Log.Info().Msgf("Получен запрос на список голосований канала %s", req.ChannelID)
+	list, err := h.Usecases.Polls.ListDB(req.ChannelID, req.UserID, filter, page)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         formatPollPage(list),
+	})
+}
+
+func (h *Handler) remindVoters(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования")
+		return
+	}
+	pollID := args[0]
+	logger.Log.Info().Msgf("Получен запрос на напоминание о голосовании %s", pollID)
+	sent, err := h.Usecases.Reminders.RemindDB(pollID, req.UserID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         fmt.Sprintf("Напоминания о голосовании %s отправлены: %d", pollID, sent),
+	})
+}
+
+func (h *Handler) minePolls(c *gin.Context, req domain.MattermostRequest, args []string) {
+	flags, _ := parseFlags(args)
+	page, err := parsePage(flags)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+		return
+	}
+	logger.Log.Info().Msgf("Получен запрос на список голосований пользователя %s", req.UserID)
+	list, err := h.Usecases.Polls.MineDB(req.UserID, page)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         formatPollPage(list),
+	})
+}
+
+func (h *Handler) searchPolls(c *gin.Context, req domain.MattermostRequest, args []string) {
+	flags, args := parseFlags(args, "team")
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите текст для поиска")
+		return
+	}
+	page, err := parsePage(flags)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: "+err.Error())
+		return
+	}
+	query := strings.Join(args, " ")
+	channelID := req.ChannelID
+	if _, ok := flags["team"]; ok {
+		channelID = ""
+	}
+	logger.Log.Info().Msgf("Получен запрос на поиск голосований: %s", query)
+	list, err := h.Usecases.Polls.SearchDB(query, channelID, req.TeamID, req.UserID, page)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         formatPollPage(list),
+	})
+}
+
+func parsePage(flags map[string]string) (int, error) {
+	value, ok := flags["page"]
+	if !ok {
+		return 1, nil
+	}
+	page, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("после --page нужно указать номер страницы")
+	}
+	return page, nil
+}
+
+func formatPollPage(list domain.PollPage) string {
+	if len(list.Polls) == 0 {
+		return "Голосований не найдено"
+	}
+	var text string
+	for _, poll := range list.Polls {
+		id := poll.ShortID
+		if id == "" {
+			id = poll.ID
+		}
+		text += fmt.Sprintf("%s — %s — %s, проголосовало %d", id, poll.Question, domain.StatusTitle(poll.Status), poll.Voters)
+		var roles []string
+		if poll.Created {
+			roles = append(roles, "вы создатель")
+		}
+		if poll.Voted {
+			roles = append(roles, "вы проголосовали")
+		}
+		if len(roles) > 0 {
+			text += "; " + strings.Join(roles, ", ")
+		}
+		if poll.ActiveAt > 0 {
+			text += fmt.Sprintf("; активность %s", formatTime(poll.ActiveAt))
+		}
+		text += "\n"
+	}
+	return text + fmt.Sprintf("Страница %d из %d", list.Page, list.Pages)
+}
+
+func (h *Handler) editPoll(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 3 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования, изменение (question, add, remove или rename) и новое значение")
+		return
+	}
+	pollID := args[0]
+	edit := domain.PollEdit{Action: args[1]}
+	var responseText string
+	switch edit.Action {
+	case domain.EditQuestion:
+		edit.Value = args[2]
+		responseText = fmt.Sprintf("Вопрос голосования %s изменен: %s", pollID, edit.Value)
+	case domain.EditAdd:
+		edit.Value = args[2]
+		responseText = fmt.Sprintf("В голосование %s добавлен вариант ответа %s", pollID, edit.Value)
+	case domain.EditRemove:
+		edit.Option = args[2]
+		responseText = fmt.Sprintf("Из голосования %s удален вариант ответа %s", pollID, edit.Option)
+	case domain.EditRename:
+		if len(args) < 4 {
+			newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите текущее и новое название варианта")
+			return
+		}
+		edit.Option, edit.Value = args[2], args[3]
+		responseText = fmt.Sprintf("Вариант ответа %s в голосовании %s переименован в %s", edit.Option, pollID, edit.Value)
+	default:
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: неизвестное изменение "+edit.Action)
+		return
+	}
+	logger.Log.Info().Msgf("Получен запрос на изменение голосования %s: %s", pollID, edit.Action)
+	err := h.Usecases.Polls.EditDB(pollID, req.UserID, edit)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "in_channel",
+		Text:         responseText,
+	})
+}
+
+func (h *Handler) approveOption(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 2 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования и предложенный вариант")
+		return
+	}
+	pollID := args[0]
+	option := args[1]
+	logger.Log.Info().Msgf("Получен запрос на одобрение варианта %s в голосовании %s", option, pollID)
+	err := h.Usecases.Polls.ApproveDB(pollID, req.UserID, option)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("В голосование %s добавлен вариант ответа %s", pollID, option)
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "in_channel",
+		Text:         responseText,
+	})
+}
+
+func (h *Handler) tieBreak(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 2 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования и вариант-победитель")
+		return
+	}
+	pollID := args[0]
+	option := args[1]
+	logger.Log.Info().Msgf("Получен запрос на разрешение ничьей в голосовании %s в пользу %s", pollID, option)
+	err := h.Usecases.Polls.TieBreakDB(pollID, req.UserID, option)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Ничья в голосовании %s разрешена создателем: победитель %s", pollID, option)
+
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "in_channel",
+		Text:         responseText,
+	})
+}
+
+func (h *Handler) archivePoll(c *gin.Context, req domain.MattermostRequest, args []string) {
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите ID голосования")
+		return
+	}
+	pollID := args[0]
+	logger.Log.Info().Msgf("Получен запрос на архивацию голосования %s", pollID)
+	err := h.Usecases.Polls.ArchiveDB(pollID, req.UserID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Голосование %s отправлено в архив", pollID)
 
 	c.JSON(http.StatusOK, domain.MattermostResponse{
 		ResponseType: "in_channel",
@@ -152,7 +915,7 @@ func (h *Handler) deletePoll(c *gin.Context, req domain.MattermostRequest, args
 	err := h.Usecases.Polls.DeleteDB(pollID, req.UserID)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("")
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newErrorResponse(c, errorStatus(err), err.Error())
 		return
 	}
 	responseText := fmt.Sprintf("Голосование %s удаленео", pollID)
@@ -163,6 +926,198 @@ func (h *Handler) deletePoll(c *gin.Context, req domain.MattermostRequest, args
 	})
 }
 
+// parseBallotArgs разбирает варианты ответа из команды голосования. Оценки
+// передаются как "вариант"=4 или вариант=4, отметки слотов — как
+// "слот"=maybe; кавычки разделяют такую запись на два аргумента, поэтому
+// аргумент вида =4 относится к предыдущему варианту.
+func parseBallotArgs(args []string) ([]string, []int, error) {
+	var options []string
+	var values []int
+	for i := 0; i < len(args); i++ {
+		option, rawScore, scored := args[i], "", false
+		if i+1 < len(args) && strings.HasPrefix(args[i+1], "=") {
+			rawScore, scored = args[i+1][1:], true
+			i++
+		} else if idx := strings.LastIndex(option, "="); idx > 0 {
+			if _, err := parseBallotValue(option[idx+1:]); err == nil {
+				option, rawScore, scored = option[:idx], option[idx+1:], true
+			}
+		}
+		options = append(options, option)
+		if !scored {
+			continue
+		}
+		score, err := parseBallotValue(rawScore)
+		if err != nil {
+			return nil, nil, fmt.Errorf("некорректная оценка варианта %s", option)
+		}
+		values = append(values, score)
+	}
+	if len(values) > 0 && len(values) != len(options) {
+		return nil, nil, fmt.Errorf("укажите оценку для каждого варианта")
+	}
+	return options, values, nil
+}
+
+func parseBallotValue(value string) (int, error) {
+	if availability, ok := domain.ParseAvailability(value); ok {
+		return availability, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseDeadline принимает срок окончания голосования в виде длительности
+// (2h, 90m) или абсолютного времени.
+func parseDeadline(value string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration <= 0 {
+			return time.Time{}, fmt.Errorf("длительность голосования должна быть положительной")
+		}
+		return now.Add(duration), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04"} {
+		if deadline, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return deadline, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("некорректный срок %s: укажите длительность (например, 2h) или время в формате 2006-01-02 15:04", value)
+}
+
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).Format("2006-01-02 15:04 MST")
+}
+
+// parseFlags отделяет от аргументов флаги вида --name value. Флаги из
+// boolFlags значения не принимают.
+func parseFlags(args []string, boolFlags ...string) (map[string]string, []string) {
+	flags := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, ok := strings.CutPrefix(args[i], "--")
+		if !ok || name == "" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if slices.Contains(boolFlags, name) {
+			flags[name] = "true"
+		} else if i+1 < len(args) {
+			flags[name] = args[i+1]
+			i++
+		} else {
+			flags[name] = ""
+		}
+	}
+	return flags, rest
+}
+
+// createSettings переносит в голосование настройки шаблона, затем флаги
+// create, которые их перекрывают. Слоты из --slots заменяют варианты
+// шаблона и делают голосование голосованием типа schedule.
+func createSettings(poll domain.Poll, template *domain.Template, flags map[string]string) (domain.Poll, error) {
+	options := poll.Options
+	if template != nil {
+		poll = template.Apply(poll)
+	}
+	if err := parseSettings(&poll, flags); err != nil {
+		return domain.Poll{}, err
+	}
+	rawSlots, withSlots := flags["slots"]
+	if !withSlots {
+		return poll, nil
+	}
+	if kind, ok := flags["kind"]; ok && kind != domain.KindSchedule {
+		return domain.Poll{}, fmt.Errorf("флаг --slots применяется только к голосованию типа schedule")
+	}
+	slots, err := domain.ParseSlots(rawSlots, time.Local)
+	if err != nil {
+		return domain.Poll{}, err
+	}
+	poll.Kind = domain.KindSchedule
+	if _, ok := flags["multi"]; !ok {
+		poll.MaxChoices = 1
+	}
+	poll.Options = append(slices.Clone(options), slots...)
+	return poll, nil
+}
+
+// parseSettings переносит в голосование настройки из флагов, общие для
+// create и template save. Настройки без флага остаются прежними, поэтому
+// флаги create перекрывают шаблон, а --no-blind и --no-public его отключают.
+func parseSettings(poll *domain.Poll, flags map[string]string) error {
+	if value, ok := flags["kind"]; ok {
+		poll.Kind = value
+		// Число вариантов из шаблона не переносится на другой тип голосования;
+		// явный --multi разбирается ниже.
+		if value != domain.KindPlurality {
+			poll.MaxChoices = 1
+		}
+	}
+	if value, ok := flags["tiebreak"]; ok {
+		poll.TieBreak = value
+	}
+	if value, ok := flags["writein"]; ok {
+		poll.WriteIn = value
+	}
+	if _, ok := flags["blind"]; ok {
+		poll.Blind = true
+	}
+	if _, ok := flags["no-blind"]; ok {
+		poll.Blind = false
+	}
+	if _, ok := flags["public"]; ok {
+		poll.Public = true
+	}
+	if _, ok := flags["no-public"]; ok {
+		poll.Public = false
+	}
+	if value, ok := flags["multi"]; ok {
+		maxChoices, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("после --multi нужно указать число вариантов")
+		}
+		poll.MaxChoices = maxChoices
+	}
+	if value, ok := flags["quorum"]; ok {
+		quorum, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("после --quorum нужно указать число участников")
+		}
+		poll.Quorum = quorum
+	}
+	if value, ok := flags["threshold"]; ok {
+		threshold, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil {
+			return fmt.Errorf("после --threshold нужно указать процент, например 66%%")
+		}
+		poll.Threshold = threshold
+	}
+	return nil
+}
+
+// cutMentions извлекает из аргументов флаг со списком упоминаний вида
+// --name @a @b и возвращает имена пользователей без @. Флаг без упоминаний
+// считается ошибкой: иначе следующие за ним аргументы молча стали бы
+// вариантами ответа, а список остался бы пустым.
+func cutMentions(args []string, name string) ([]string, []string, error) {
+	var mentions, rest []string
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--"+name {
+			rest = append(rest, args[i])
+			continue
+		}
+		found := false
+		for i+1 < len(args) && strings.HasPrefix(args[i+1], "@") {
+			mentions = append(mentions, strings.TrimPrefix(args[i+1], "@"))
+			found = true
+			i++
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("после --%s укажите пользователей или группы через @, например --%s @alice @oncall", name, name)
+		}
+	}
+	return mentions, rest, nil
+}
+
 func parseQuotedArgs(input string) []string {
 	re := regexp.MustCompile(`"([^"]*)"|\S+`)
 	matches := re.FindAllStringSubmatch(input, -1)
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateSettings(t *testing.T) {
+	meeting := &domain.BuiltinTemplates[1]
+	tests := []struct {
+		name     string
+		poll     domain.Poll
+		template *domain.Template
+		flags    map[string]string
+		want     domain.Poll
+	}{
+		{
+			name:     "template",
+			template: meeting,
+			want:     domain.Poll{Options: meeting.Options, Kind: domain.KindPlurality, MaxChoices: 3},
+		},
+		{
+			name:     "explicit multi",
+			template: meeting,
+			flags:    map[string]string{"multi": "1"},
+			want:     domain.Poll{Options: meeting.Options, Kind: domain.KindPlurality, MaxChoices: 1},
+		},
+		{
+			name:     "kind overrides template",
+			template: meeting,
+			flags:    map[string]string{"kind": domain.KindRanked},
+			want:     domain.Poll{Options: meeting.Options, Kind: domain.KindRanked, MaxChoices: 1},
+		},
+		{
+			name:     "blind turned off",
+			template: &domain.BuiltinTemplates[2],
+			flags:    map[string]string{"no-blind": ""},
+			want:     domain.Poll{Options: domain.BuiltinTemplates[2].Options, Kind: domain.KindPlurality, MaxChoices: 1},
+		},
+		{
+			name:     "slots replace template options",
+			template: meeting,
+			flags:    map[string]string{"slots": "2026-10-20 10:00..12:00/1h"},
+			want:     domain.Poll{Options: []string{"2026-10-20 10:00–11:00", "2026-10-20 11:00–12:00"}, Kind: domain.KindSchedule, MaxChoices: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll, err := createSettings(tt.poll, tt.template, tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(poll.Options, tt.want.Options) || poll.Kind != tt.want.Kind || poll.MaxChoices != tt.want.MaxChoices || poll.Blind != tt.want.Blind {
+				t.Errorf("createSettings() = %+v, want %+v", poll, tt.want)
+			}
+			if err := domain.CheckSettings(poll); err != nil {
+				t.Errorf("CheckSettings: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateSettingsRejectsSlotsWithKind(t *testing.T) {
+	flags := map[string]string{"kind": domain.KindRanked, "slots": "2026-10-20 10:00..11:00"}
+	if _, err := createSettings(domain.Poll{}, nil, flags); err == nil {
+		t.Error("--slots with --kind ranked accepted")
+	}
+}
+
+func TestRespondSuggested(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	if respondSuggested(c, domain.ErrForbidden) {
+		t.Fatal("respondSuggested handled an unrelated error")
+	}
+	if !respondSuggested(c, fmt.Errorf("%w: Пицца", domain.ErrSuggested)) {
+		t.Fatal("respondSuggested did not handle a suggestion")
+	}
+	var resp domain.MattermostResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || resp.ResponseType != "ephemeral" || !strings.HasSuffix(resp.Text, "Пицца") {
+		t.Errorf("got %d %+v, want an ephemeral response about the suggestion", w.Code, resp)
+	}
+}
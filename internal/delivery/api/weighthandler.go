@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	logger "github.com/bllooop/votingbot/pkg/logging"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) weightCommand(c *gin.Context, req domain.MattermostRequest, args []string) {
+	flags, args := parseFlags(args)
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите действие set, remove или list")
+		return
+	}
+	scope, scopeTitle := domain.ChannelScope(req.ChannelID), "канала"
+	if pollID, ok := flags["poll"]; ok {
+		scope, scopeTitle = domain.PollScope(pollID), "голосования "+pollID
+	}
+
+	switch args[0] {
+	case "set":
+		h.setWeight(c, req, scope, scopeTitle, args[1:])
+	case "remove":
+		h.removeWeight(c, req, scope, scopeTitle, args[1:])
+	case "list":
+		h.listWeights(c, scope, scopeTitle)
+	default:
+		c.JSON(http.StatusOK, gin.H{"response_type": "ephemeral", "text": "Неизвестное действие с весами голосов"})
+	}
+}
+
+func (h *Handler) setWeight(c *gin.Context, req domain.MattermostRequest, scope string, scopeTitle string, args []string) {
+	if len(args) < 2 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите пользователя и вес голоса")
+		return
+	}
+	weight, err := strconv.Atoi(args[1])
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: вес голоса должен быть целым числом")
+		return
+	}
+	logger.Log.Info().Msgf("Получен запрос на установку веса %d пользователю %s в области %s", weight, args[0], scope)
+	users, err := h.Usecases.Weights.SetWeightDB(domain.Weight{Scope: scope, UserID: args[0], Weight: weight}, req.UserID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Вес голоса %s для %s: %d", args[0], scopeTitle, weight) + formatWeightUsers(args[0], users)
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         responseText,
+	})
+}
+
+func (h *Handler) removeWeight(c *gin.Context, req domain.MattermostRequest, scope string, scopeTitle string, args []string) {
+	if len(args) < 1 {
+		newErrorResponse(c, http.StatusBadRequest, "Ошибка: укажите пользователя")
+		return
+	}
+	logger.Log.Info().Msgf("Получен запрос на удаление веса пользователя %s в области %s", args[0], scope)
+	users, err := h.Usecases.Weights.RemoveWeightDB(scope, args[0], req.UserID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Вес голоса %s для %s сброшен", args[0], scopeTitle) + formatWeightUsers(args[0], users)
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         responseText,
+	})
+}
+
+func (h *Handler) listWeights(c *gin.Context, scope string, scopeTitle string) {
+	logger.Log.Info().Msgf("Получен запрос на список весов в области %s", scope)
+	weights, err := h.Usecases.Weights.ListWeightsDB(scope)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("")
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	responseText := fmt.Sprintf("Веса голосов для %s:\n", scopeTitle)
+	if len(weights) == 0 {
+		responseText += "не заданы, у всех участников вес 1"
+	}
+	for _, weight := range weights {
+		responseText += fmt.Sprintf("%s: %d\n", weight.UserID, weight.Weight)
+	}
+	c.JSON(http.StatusOK, domain.MattermostResponse{
+		ResponseType: "ephemeral",
+		Text:         responseText,
+	})
+}
+
+// formatWeightUsers уточняет, скольким пользователям изменен вес, если он
+// задавался через @группу.
+func formatWeightUsers(target string, users int) string {
+	if !strings.HasPrefix(target, "@") || users <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(", пользователей: %d", users)
+}
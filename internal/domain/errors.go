@@ -0,0 +1,11 @@
+package domain
+
+import "errors"
+
+var (
+	ErrAlreadyVoted = errors.New("вы уже проголосовали в этом голосовании")
+	ErrNotVoted     = errors.New("вы еще не голосовали в этом голосовании")
+	ErrForbidden    = errors.New("недостаточно прав")
+	ErrSuggested    = errors.New("вариант отправлен создателю голосования на одобрение")
+	ErrIneligible   = errors.New("вы не входите в число участников этого голосования")
+)
@@ -1,10 +1,17 @@
 package domain
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type MattermostRequest struct {
 	Command   string `json:"command"`
 	Text      string `json:"text"`
 	UserID    string `json:"user_id"`
 	ChannelID string `json:"channel_id"`
+	TeamID    string `json:"team_id"`
 }
 
 type MattermostResponse struct {
@@ -16,7 +23,246 @@ type Result struct {
 	Question  string `json:"question"`
 	Option    string `json:"option"`
 	Count     int    `json:"count"`
+	Weighted  int    `json:"weighted"`
 	ExpiresAt string `json:"expires_at"`
 }
 
 type Results []Result
+
+type ScoreResult struct {
+	Option    string  `json:"option"`
+	Count     int     `json:"count"`
+	Mean      float64 `json:"mean"`
+	Median    float64 `json:"median"`
+	Histogram []int   `json:"histogram"`
+}
+
+type Round struct {
+	Number     int      `json:"number"`
+	Counts     Results  `json:"counts"`
+	Eliminated []string `json:"eliminated"`
+}
+
+type PollResults struct {
+	PollID     string         `json:"poll_id"`
+	ShortID    string         `json:"short_id,omitempty"`
+	Question   string         `json:"question"`
+	Kind       string         `json:"kind"`
+	Status     string         `json:"status"`
+	ExpiresAt  string         `json:"expires_at,omitempty"`
+	History    []StatusChange `json:"history,omitempty"`
+	MaxChoices int            `json:"max_choices"`
+	Voters     int            `json:"voters"`
+	Electorate int            `json:"electorate,omitempty"`
+	Weighted   bool           `json:"weighted"`
+	Options    Results        `json:"options"`
+	Rounds     []Round        `json:"rounds,omitempty"`
+	Scores     []ScoreResult  `json:"scores,omitempty"`
+	Slots      []SlotResult   `json:"slots,omitempty"`
+	Pairwise   [][]int        `json:"pairwise,omitempty"`
+	Paths      [][]int        `json:"paths,omitempty"`
+	Winner     string         `json:"winner,omitempty"`
+	Tied       []string       `json:"tied,omitempty"`
+	TieBreak   string         `json:"tiebreak,omitempty"`
+	Seed       uint64         `json:"seed,omitempty"`
+	RunoffID   string         `json:"runoff_id,omitempty"`
+	WriteIn    string         `json:"writein,omitempty"`
+	Suggested  []string       `json:"suggestions,omitempty"`
+	CreatorID  string         `json:"-"`
+	Blind      bool           `json:"blind"`
+	Hidden     bool           `json:"hidden"`
+	Decision   *Decision      `json:"decision,omitempty"`
+}
+
+type Decision struct {
+	Quorum    int     `json:"quorum"`
+	Threshold int     `json:"threshold"`
+	QuorumMet bool    `json:"quorum_met"`
+	Leader    string  `json:"leader"`
+	Share     float64 `json:"share"`
+	Passed    bool    `json:"passed"`
+}
+
+const (
+	KindPlurality = "plurality"
+	KindRanked    = "ranked"
+	KindScore     = "score"
+	KindSchulze   = "schulze"
+	KindSchedule  = "schedule"
+)
+
+// IsRanked сообщает, принимает ли голосование бюллетени с вариантами в порядке
+// предпочтения. Такие бюллетени хранятся одинаково для всех ранжированных методов.
+func IsRanked(kind string) bool {
+	return kind == KindRanked || kind == KindSchulze
+}
+
+const (
+	TieBreakCreator  = "creator"
+	TieBreakRandom   = "random"
+	TieBreakEarliest = "earliest"
+	TieBreakRunoff   = "runoff"
+)
+
+// RunoffPending занимает поле RunoffID, пока создается второй тур, чтобы
+// одновременные закрытия голосования не создали два тура.
+const RunoffPending = "pending"
+
+const (
+	WriteInAdd     = "add"
+	WriteInSuggest = "suggest"
+)
+
+// CheckSettings проверяет настройки голосования, которые задаются флагами
+// create и сохраняются в шаблонах. MaxChoices 0 означает один вариант.
+func CheckSettings(poll Poll) error {
+	if poll.MaxChoices < 0 || poll.MaxChoices > len(poll.Options) {
+		return fmt.Errorf("количество выбираемых вариантов должно быть от 1 до %d", len(poll.Options))
+	}
+	switch poll.Kind {
+	case "", KindPlurality:
+	case KindRanked, KindSchulze, KindScore, KindSchedule:
+		if poll.MaxChoices > 1 {
+			return fmt.Errorf("флаг --multi не применяется к голосованию типа %s", poll.Kind)
+		}
+	default:
+		return fmt.Errorf("неизвестный тип голосования %s", poll.Kind)
+	}
+	if poll.Threshold < 0 || poll.Threshold > 100 {
+		return fmt.Errorf("порог принятия решения должен быть от 1%% до 100%%")
+	}
+	if poll.Threshold > 0 && (poll.Kind == KindScore || poll.Kind == KindSchulze || poll.Kind == KindSchedule) {
+		return fmt.Errorf("порог принятия решения не применяется к голосованию типа %s", poll.Kind)
+	}
+	if poll.Kind == KindSchedule {
+		if poll.WriteIn != "" {
+			return fmt.Errorf("добавление вариантов участниками не применяется к голосованию типа %s", poll.Kind)
+		}
+		for _, option := range poll.Options {
+			if _, _, err := ParseSlot(option, time.Local); err != nil {
+				return err
+			}
+		}
+	}
+	if poll.Quorum < 0 {
+		return fmt.Errorf("кворум не может быть отрицательным")
+	}
+	switch poll.TieBreak {
+	case "", TieBreakCreator, TieBreakEarliest, TieBreakRunoff, TieBreakRandom:
+	default:
+		return fmt.Errorf("неизвестное правило разрешения ничьей %s", poll.TieBreak)
+	}
+	switch poll.WriteIn {
+	case "", WriteInAdd, WriteInSuggest:
+	default:
+		return fmt.Errorf("неизвестный режим добавления вариантов %s", poll.WriteIn)
+	}
+	return nil
+}
+
+const (
+	ListActive = "active"
+	ListClosed = "closed"
+	ListAll    = "all"
+	PageSize   = 10
+)
+
+// PollSummary — строка в списке голосований канала.
+type PollSummary struct {
+	ID       string `json:"id"`
+	ShortID  string `json:"short_id"`
+	Question string `json:"question"`
+	Status   string `json:"status"`
+	Voters   int    `json:"voters"`
+	Created  bool   `json:"created,omitempty"`
+	Voted    bool   `json:"voted,omitempty"`
+	ActiveAt int64  `json:"active_at,omitempty"`
+}
+
+type PollPage struct {
+	Polls []PollSummary `json:"polls"`
+	Page  int           `json:"page"`
+	Pages int           `json:"pages"`
+}
+
+const ShortIDPrefix = "P-"
+
+// IsShortID отличает короткий ID голосования от UUID.
+func IsShortID(id string) bool {
+	return len(id) < 36 && strings.HasPrefix(strings.ToUpper(id), ShortIDPrefix)
+}
+
+const (
+	EditQuestion = "question"
+	EditAdd      = "add"
+	EditRemove   = "remove"
+	EditRename   = "rename"
+)
+
+// PollEdit описывает изменение вопроса или вариантов ответа. Для
+// EditRename Option — текущее название варианта, Value — новое.
+type PollEdit struct {
+	Action string
+	Option string
+	Value  string
+}
+
+const (
+	MinScore = 1
+	MaxScore = 5
+)
+
+type Poll struct {
+	ID          string
+	Question    string
+	Options     []string
+	CreatorID   string
+	Status      string
+	MaxChoices  int
+	Kind        string
+	ChannelID   string
+	ExpiresAt   int64
+	OpensAt     int64
+	History     []StatusChange
+	Blind       bool
+	Public      bool
+	Quorum      int
+	Threshold   int
+	TieBreak    string
+	Seed        uint64
+	TieWinner   string
+	RunoffID    string
+	WriteIn     string
+	Suggestions []string
+	// OptionIDs хранит постоянные идентификаторы вариантов в том же порядке,
+	// что и Options. Бюллетени ссылаются на идентификаторы, поэтому
+	// переименование варианта не теряет отданные за него голоса.
+	OptionIDs    []int
+	NextOptionID int
+	ShortID      string
+	TeamID       string
+	// Invited — имена пользователей без @, которым адресовано голосование.
+	// Если список пуст, напоминания получают участники канала.
+	Invited      []string
+	RemindBefore int64
+	Reminded     bool
+	// EligibleNames — пользователи и группы без @, которым разрешено
+	// голосовать, Eligible — ID их участников на момент создания голосования.
+	EligibleNames []string
+	Eligible      []string
+}
+
+type StatusChange struct {
+	Status string `json:"status"`
+	At     int64  `json:"at"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+type Ballot struct {
+	PollID  string
+	UserID  string
+	Options []string
+	Values  []int
+	CastAt  int64
+	Weight  int
+}
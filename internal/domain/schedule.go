@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Отметки участника в голосовании типа KindSchedule. Слоты, которые участник
+// не отметил, считаются неподходящими.
+const (
+	AvailabilityNo = iota
+	AvailabilityMaybe
+	AvailabilityYes
+)
+
+const (
+	SlotLayout = "2006-01-02 15:04"
+	MaxSlots   = 50
+)
+
+type SlotResult struct {
+	Option string `json:"option"`
+	Yes    int    `json:"yes"`
+	Maybe  int    `json:"maybe"`
+	No     int    `json:"no"`
+}
+
+// ParseAvailability принимает отметку слота: yes, maybe или no, в том числе
+// по-русски.
+func ParseAvailability(value string) (int, bool) {
+	switch strings.ToLower(value) {
+	case "yes", "y", "да", "+":
+		return AvailabilityYes, true
+	case "maybe", "возможно", "?":
+		return AvailabilityMaybe, true
+	case "no", "n", "нет", "-":
+		return AvailabilityNo, true
+	}
+	return 0, false
+}
+
+func AvailabilityTitle(value int) string {
+	switch value {
+	case AvailabilityYes:
+		return "да"
+	case AvailabilityMaybe:
+		return "возможно"
+	default:
+		return "нет"
+	}
+}
+
+// FormatSlot записывает слот как вариант ответа: 2026-10-20 10:00–11:00.
+// Дата окончания указывается, только если слот переходит на следующий день.
+func FormatSlot(start, end time.Time) string {
+	if start.Format(time.DateOnly) == end.Format(time.DateOnly) {
+		return start.Format(SlotLayout) + "–" + end.Format("15:04")
+	}
+	return start.Format(SlotLayout) + "–" + end.Format(SlotLayout)
+}
+
+// ParseSlot разбирает вариант ответа, записанный FormatSlot.
+func ParseSlot(option string, loc *time.Location) (time.Time, time.Time, error) {
+	rawStart, rawEnd, ok := strings.Cut(option, "–")
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("слот %s должен иметь вид 2006-01-02 15:04–16:04", option)
+	}
+	start, err := time.ParseInLocation(SlotLayout, rawStart, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("некорректное начало слота %s", option)
+	}
+	end, err := parseSlotEnd(start, rawEnd, loc)
+	if err != nil || !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("некорректное окончание слота %s", option)
+	}
+	return start, end, nil
+}
+
+// ParseSlots разбирает диапазоны вида "2026-10-20 10:00..16:00/1h",
+// разделенные точкой с запятой, в слоты заданной длины. Без шага диапазон
+// становится одним слотом; остаток короче шага отбрасывается.
+func ParseSlots(spec string, loc *time.Location) ([]string, error) {
+	var slots []string
+	for _, rawRange := range strings.Split(spec, ";") {
+		rawRange = strings.TrimSpace(rawRange)
+		if rawRange == "" {
+			continue
+		}
+		rawRange, rawStep, stepped := strings.Cut(rawRange, "/")
+		rawStart, rawEnd, ok := strings.Cut(rawRange, "..")
+		if !ok {
+			return nil, fmt.Errorf("диапазон %s должен иметь вид 2006-01-02 10:00..16:00/1h", rawRange)
+		}
+		start, err := time.ParseInLocation(SlotLayout, strings.TrimSpace(rawStart), loc)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное начало диапазона %s", rawRange)
+		}
+		end, err := parseSlotEnd(start, strings.TrimSpace(rawEnd), loc)
+		if err != nil || !end.After(start) {
+			return nil, fmt.Errorf("некорректное окончание диапазона %s", rawRange)
+		}
+		step := end.Sub(start)
+		if stepped {
+			step, err = time.ParseDuration(strings.TrimSpace(rawStep))
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("некорректный шаг %s, укажите длительность, например 1h", rawStep)
+			}
+		}
+		if start.Add(step).After(end) {
+			return nil, fmt.Errorf("диапазон %s короче шага %s", rawRange, step)
+		}
+		for slotStart := start; !slotStart.Add(step).After(end); slotStart = slotStart.Add(step) {
+			slot := FormatSlot(slotStart, slotStart.Add(step))
+			if slices.Contains(slots, slot) {
+				return nil, fmt.Errorf("слот %s указан несколько раз", slot)
+			}
+			slots = append(slots, slot)
+			if len(slots) > MaxSlots {
+				return nil, fmt.Errorf("в голосовании может быть не больше %d слотов", MaxSlots)
+			}
+		}
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("не указано ни одного слота")
+	}
+	return slots, nil
+}
+
+// parseSlotEnd принимает окончание слота как время того же дня или как
+// полную дату и время.
+func parseSlotEnd(start time.Time, value string, loc *time.Location) (time.Time, error) {
+	if end, err := time.ParseInLocation(SlotLayout, value, loc); err == nil {
+		return end, nil
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(start.Year(), start.Month(), start.Day(), clock.Hour(), clock.Minute(), 0, 0, loc), nil
+}
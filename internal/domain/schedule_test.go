@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSlots(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		slots []string
+		ok    bool
+	}{
+		{"one slot", "2026-10-20 10:00..11:00", []string{"2026-10-20 10:00–11:00"}, true},
+		{"stepped", "2026-10-20 10:00..13:00/1h", []string{"2026-10-20 10:00–11:00", "2026-10-20 11:00–12:00", "2026-10-20 12:00–13:00"}, true},
+		{"remainder dropped", "2026-10-20 10:00..11:45/30m", []string{"2026-10-20 10:00–10:30", "2026-10-20 10:30–11:00", "2026-10-20 11:00–11:30"}, true},
+		{"several ranges", "2026-10-20 10:00..11:00; 2026-10-21 15:00..16:00", []string{"2026-10-20 10:00–11:00", "2026-10-21 15:00–16:00"}, true},
+		{"over midnight", "2026-10-20 23:00..2026-10-21 01:00/1h", []string{"2026-10-20 23:00–2026-10-21 00:00", "2026-10-21 00:00–01:00"}, true},
+		{"no range", "2026-10-20 10:00", nil, false},
+		{"bad start", "20.10.2026 10:00..11:00", nil, false},
+		{"end before start", "2026-10-20 11:00..10:00", nil, false},
+		{"bad step", "2026-10-20 10:00..12:00/hour", nil, false},
+		{"range shorter than step", "2026-10-20 10:00..10:30/1h", nil, false},
+		{"duplicate", "2026-10-20 10:00..11:00; 2026-10-20 10:00..11:00", nil, false},
+		{"too many", "2026-10-20 00:00..2026-10-23 00:00/1h", nil, false},
+		{"empty", " ; ", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots, err := ParseSlots(tt.spec, time.UTC)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ParseSlots(%q) error = %v, want ok %v", tt.spec, err, tt.ok)
+			}
+			if !reflect.DeepEqual(slots, tt.slots) {
+				t.Errorf("ParseSlots(%q) = %q, want %q", tt.spec, slots, tt.slots)
+			}
+		})
+	}
+}
+
+func TestParseSlot(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	start := time.Date(2026, 10, 20, 23, 0, 0, 0, loc)
+	end := time.Date(2026, 10, 21, 0, 30, 0, 0, loc)
+	gotStart, gotEnd, err := ParseSlot(FormatSlot(start, end), loc)
+	if err != nil || !gotStart.Equal(start) || !gotEnd.Equal(end) {
+		t.Errorf("ParseSlot(FormatSlot) = %v, %v, %v; want %v, %v", gotStart, gotEnd, err, start, end)
+	}
+
+	for _, option := range []string{"Утро", "2026-10-20 10:00", "2026-10-20 10:00–09:00", "2026-10-20 10:00–вечер"} {
+		if _, _, err := ParseSlot(option, loc); err == nil {
+			t.Errorf("ParseSlot(%q) succeeded, want error", option)
+		}
+	}
+}
+
+func TestParseAvailability(t *testing.T) {
+	for value, want := range map[string]int{"yes": AvailabilityYes, "Да": AvailabilityYes, "?": AvailabilityMaybe, "нет": AvailabilityNo} {
+		t.Run(value, func(t *testing.T) {
+			got, ok := ParseAvailability(value)
+			if !ok || got != want {
+				t.Errorf("ParseAvailability(%q) = %d, %v; want %d", value, got, ok, want)
+			}
+		})
+	}
+	if _, ok := ParseAvailability(fmt.Sprint(AvailabilityYes)); ok {
+		t.Errorf("ParseAvailability accepted a raw value")
+	}
+}
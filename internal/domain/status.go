@@ -0,0 +1,42 @@
+package domain
+
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusActive    = "active"
+	StatusClosed    = "closed"
+	StatusArchived  = "archived"
+)
+
+var statusTransitions = map[string][]string{
+	StatusDraft:     {StatusScheduled, StatusActive},
+	StatusScheduled: {StatusActive, StatusClosed},
+	StatusActive:    {StatusClosed},
+	StatusClosed:    {StatusActive, StatusArchived},
+}
+
+func CanTransition(from string, to string) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+func StatusTitle(status string) string {
+	switch status {
+	case StatusDraft:
+		return "черновик"
+	case StatusScheduled:
+		return "запланировано"
+	case StatusActive:
+		return "активно"
+	case StatusClosed:
+		return "закрыто"
+	case StatusArchived:
+		return "в архиве"
+	default:
+		return status
+	}
+}
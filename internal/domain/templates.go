@@ -0,0 +1,42 @@
+package domain
+
+// Template — заготовка голосования: варианты ответа и настройки, которые
+// подставляются в create --template. У встроенных шаблонов нет владельца.
+type Template struct {
+	Name       string   `json:"name"`
+	OwnerID    string   `json:"owner_id,omitempty"`
+	Options    []string `json:"options"`
+	Kind       string   `json:"kind"`
+	MaxChoices int      `json:"max_choices"`
+	Blind      bool     `json:"blind"`
+	Public     bool     `json:"public"`
+	Quorum     int      `json:"quorum"`
+	Threshold  int      `json:"threshold"`
+	TieBreak   string   `json:"tiebreak,omitempty"`
+	WriteIn    string   `json:"writein,omitempty"`
+}
+
+var BuiltinTemplates = []Template{
+	{Name: "yesno", Options: []string{"За", "Против", "Воздержался"}, Kind: KindPlurality, MaxChoices: 1},
+	{Name: "meeting", Options: []string{"Утро (9:00–12:00)", "День (12:00–15:00)", "Вечер (15:00–18:00)"}, Kind: KindPlurality, MaxChoices: 3},
+	{Name: "mood", Options: []string{"Отлично", "Хорошо", "Нормально", "Плохо"}, Kind: KindPlurality, MaxChoices: 1, Blind: true},
+}
+
+// Apply подставляет в голосование варианты и настройки шаблона. Варианты
+// шаблона берутся, только если в команде create их нет; флаги create
+// разбираются после Apply и перекрывают настройки шаблона. Число
+// выбираемых вариантов не превышает число вариантов голосования.
+func (t Template) Apply(poll Poll) Poll {
+	if len(poll.Options) == 0 {
+		poll.Options = t.Options
+	}
+	poll.Kind = t.Kind
+	poll.MaxChoices = min(max(t.MaxChoices, 1), len(poll.Options))
+	poll.Blind = t.Blind
+	poll.Public = t.Public
+	poll.Quorum = t.Quorum
+	poll.Threshold = t.Threshold
+	poll.TieBreak = t.TieBreak
+	poll.WriteIn = t.WriteIn
+	return poll
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplateApply(t *testing.T) {
+	meeting := Template{Options: []string{"Утро", "День", "Вечер"}, Kind: KindPlurality, MaxChoices: 3, Blind: true, Quorum: 5}
+
+	poll := meeting.Apply(Poll{})
+	if !reflect.DeepEqual(poll.Options, meeting.Options) || poll.MaxChoices != 3 || !poll.Blind || poll.Quorum != 5 {
+		t.Errorf("Apply(Poll{}) = %+v", poll)
+	}
+
+	poll = meeting.Apply(Poll{Options: []string{"Пн", "Вт"}})
+	if !reflect.DeepEqual(poll.Options, []string{"Пн", "Вт"}) {
+		t.Errorf("Options = %q, want explicit options", poll.Options)
+	}
+	if poll.MaxChoices != 2 {
+		t.Errorf("MaxChoices = %d, want 2", poll.MaxChoices)
+	}
+	if err := CheckSettings(poll); err != nil {
+		t.Errorf("CheckSettings: %v", err)
+	}
+}
+
+func TestCheckSettings(t *testing.T) {
+	options := []string{"A", "B", "C"}
+	tests := []struct {
+		name string
+		poll Poll
+		ok   bool
+	}{
+		{"defaults", Poll{Options: options}, true},
+		{"multi", Poll{Options: options, MaxChoices: 3}, true},
+		{"multi too large", Poll{Options: options, MaxChoices: 4}, false},
+		{"multi ranked", Poll{Options: options, Kind: KindRanked, MaxChoices: 2}, false},
+		{"unknown kind", Poll{Options: options, Kind: "approval"}, false},
+		{"threshold", Poll{Options: options, Threshold: 66}, true},
+		{"threshold range", Poll{Options: options, Threshold: 101}, false},
+		{"threshold score", Poll{Options: options, Kind: KindScore, Threshold: 50}, false},
+		{"negative quorum", Poll{Options: options, Quorum: -1}, false},
+		{"unknown tiebreak", Poll{Options: options, TieBreak: "coin"}, false},
+		{"random tiebreak", Poll{Options: options, TieBreak: TieBreakRandom}, true},
+		{"unknown writein", Poll{Options: options, WriteIn: "free"}, false},
+		{"schedule options", Poll{Options: options, Kind: KindSchedule}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSettings(tt.poll)
+			if (err == nil) != tt.ok {
+				t.Errorf("CheckSettings(%+v) = %v, want ok %v", tt.poll, err, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package domain
+
+const (
+	MinWeight = 1
+	MaxWeight = 100
+)
+
+type Weight struct {
+	Scope  string `json:"scope"`
+	UserID string `json:"user_id"`
+	Weight int    `json:"weight"`
+}
+
+func ChannelScope(channelID string) string {
+	return "channel:" + channelID
+}
+
+func PollScope(pollID string) string {
+	return "poll:" + pollID
+}
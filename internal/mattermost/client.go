@@ -0,0 +1,231 @@
+package mattermost
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// API — вызовы Mattermost, которые нужны боту. Интерфейс позволяет
+// подменить настоящий сервер тестовым.
+type API interface {
+	CreatePost(channelID string, message string) error
+	SendDirect(userID string, message string) error
+	ChannelMembers(channelID string) ([]string, error)
+	UserIDs(usernames []string) (map[string]string, error)
+	GroupMembers(name string) ([]string, error)
+	IsChannelMember(channelID string, userID string) (bool, error)
+}
+
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	botID string
+}
+
+func NewClient(baseURL string, token string) *Client {
+	return &Client{
+		baseURL: baseURL,
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+type post struct {
+	ChannelID string `json:"channel_id"`
+	Message   string `json:"message"`
+}
+
+type user struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type channel struct {
+	ID string `json:"id"`
+}
+
+type channelMember struct {
+	UserID string `json:"user_id"`
+}
+
+type group struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type groupMembers struct {
+	Members []user `json:"members"`
+}
+
+const membersPerPage = 200
+
+// StatusError — ответ Mattermost с кодом ошибки.
+type StatusError struct {
+	Status int
+	Method string
+	Path   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Mattermost вернул статус %d на запрос %s %s", e.Status, e.Method, e.Path)
+}
+
+func (c *Client) CreatePost(channelID string, message string) error {
+	return c.do(http.MethodPost, "/api/v4/posts", post{ChannelID: channelID, Message: message}, nil)
+}
+
+// SendDirect отправляет личное сообщение от имени бота.
+func (c *Client) SendDirect(userID string, message string) error {
+	botID, err := c.me()
+	if err != nil {
+		return err
+	}
+	var direct channel
+	if err := c.do(http.MethodPost, "/api/v4/channels/direct", []string{botID, userID}, &direct); err != nil {
+		return err
+	}
+	return c.CreatePost(direct.ID, message)
+}
+
+// ChannelMembers возвращает ID участников канала, кроме самого бота.
+func (c *Client) ChannelMembers(channelID string) ([]string, error) {
+	botID, err := c.me()
+	if err != nil {
+		return nil, err
+	}
+	var userIDs []string
+	for page := 0; ; page++ {
+		var members []channelMember
+		path := fmt.Sprintf("/api/v4/channels/%s/members?page=%d&per_page=%d", url.PathEscape(channelID), page, membersPerPage)
+		if err := c.do(http.MethodGet, path, nil, &members); err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			if member.UserID != botID {
+				userIDs = append(userIDs, member.UserID)
+			}
+		}
+		if len(members) < membersPerPage {
+			return userIDs, nil
+		}
+	}
+}
+
+// UserIDs находит ID пользователей по их именам без @. Ненайденные имена в
+// результат не попадают.
+func (c *Client) UserIDs(usernames []string) (map[string]string, error) {
+	var users []user
+	if err := c.do(http.MethodPost, "/api/v4/users/usernames", usernames, &users); err != nil {
+		return nil, err
+	}
+	userIDs := make(map[string]string, len(users))
+	for _, u := range users {
+		userIDs[u.Username] = u.ID
+	}
+	return userIDs, nil
+}
+
+// GroupMembers возвращает ID участников группы пользователей по ее имени.
+func (c *Client) GroupMembers(name string) ([]string, error) {
+	var groups []group
+	path := fmt.Sprintf("/api/v4/groups?q=%s&per_page=%d", url.QueryEscape(name), membersPerPage)
+	if err := c.do(http.MethodGet, path, nil, &groups); err != nil {
+		return nil, err
+	}
+	groupID := ""
+	for _, g := range groups {
+		if g.Name == name {
+			groupID = g.ID
+		}
+	}
+	if groupID == "" {
+		return nil, fmt.Errorf("группа %s не найдена в Mattermost", name)
+	}
+
+	var userIDs []string
+	for page := 0; ; page++ {
+		var members groupMembers
+		path := fmt.Sprintf("/api/v4/groups/%s/members?page=%d&per_page=%d", url.PathEscape(groupID), page, membersPerPage)
+		if err := c.do(http.MethodGet, path, nil, &members); err != nil {
+			return nil, err
+		}
+		for _, member := range members.Members {
+			userIDs = append(userIDs, member.ID)
+		}
+		if len(members.Members) < membersPerPage {
+			return userIDs, nil
+		}
+	}
+}
+
+// IsChannelMember проверяет, состоит ли пользователь в канале. Запрос
+// требует права на чтение канала, которое есть у бота в каналах, где он
+// состоит, и в открытых каналах команды. Канал, недоступный боту, считается
+// недоступным и пользователю.
+func (c *Client) IsChannelMember(channelID string, userID string) (bool, error) {
+	path := fmt.Sprintf("/api/v4/channels/%s/members/%s", url.PathEscape(channelID), url.PathEscape(userID))
+	err := c.do(http.MethodGet, path, nil, nil)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && (statusErr.Status == http.StatusNotFound || statusErr.Status == http.StatusForbidden) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c *Client) me() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.botID != "" {
+		return c.botID, nil
+	}
+	var bot user
+	if err := c.do(http.MethodGet, "/api/v4/users/me", nil, &bot); err != nil {
+		return "", err
+	}
+	c.botID = bot.ID
+	return c.botID, nil
+}
+
+func (c *Client) do(method string, path string, body interface{}, out interface{}) error {
+	if c.baseURL == "" {
+		return fmt.Errorf("адрес сервера Mattermost не задан")
+	}
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return &StatusError{Status: resp.StatusCode, Method: method, Path: path}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bllooop/votingbot/internal/domain"
+)
+
+// resolveOptions сопоставляет варианты из бюллетеня с вариантами голосования.
+// Вариант можно указать точным текстом, номером в списке вариантов (#2, а
+// если ни один вариант не похож на число — и просто 2) или текстом без учета
+// регистра и лишних пробелов. Несопоставленные варианты
+// остаются как есть, чтобы их обработали writeIns и checkBallot. Слоты,
+// указанные без отметки, участника устраивают.
+func resolveOptions(poll domain.Poll, ballot domain.Ballot) domain.Ballot {
+	options := make([]string, len(ballot.Options))
+	for i, option := range ballot.Options {
+		options[i] = matchOption(poll.Options, option)
+	}
+	ballot.Options = options
+	if poll.Kind == domain.KindSchedule && len(ballot.Values) == 0 {
+		ballot.Values = make([]int, len(options))
+		for i := range ballot.Values {
+			ballot.Values[i] = domain.AvailabilityYes
+		}
+	}
+	return ballot
+}
+
+func matchOption(options []string, input string) string {
+	for _, option := range options {
+		if option == input {
+			return option
+		}
+	}
+	number, numbered := strings.CutPrefix(strings.TrimSpace(input), "#")
+	if numbered || !slices.ContainsFunc(options, isNumber) {
+		if n, err := strconv.Atoi(number); err == nil && n >= 1 && n <= len(options) {
+			return options[n-1]
+		}
+	}
+	normalized := normalizeOption(input)
+	for _, option := range options {
+		if normalizeOption(option) == normalized {
+			return option
+		}
+	}
+	return input
+}
+
+func isNumber(option string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(option), 64)
+	return err == nil
+}
+
+func normalizeOption(option string) string {
+	return strings.ToLower(strings.Join(strings.Fields(option), " "))
+}
+
+// suggestOption подбирает вариант, ближайший к введенному по расстоянию
+// Левенштейна. Слишком далекие варианты не предлагаются.
+func suggestOption(options []string, input string) (string, bool) {
+	normalized := normalizeOption(input)
+	best, bestDistance := "", -1
+	for _, option := range options {
+		distance := levenshtein(normalized, normalizeOption(option))
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = option, distance
+		}
+	}
+	limit := max(2, utf8.RuneCountInString(normalized)/3)
+	return best, bestDistance >= 0 && bestDistance <= limit
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
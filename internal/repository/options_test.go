@@ -0,0 +1,28 @@
+package repository
+
+import "testing"
+
+func TestMatchOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		input   string
+		want    string
+	}{
+		{"exact text", []string{"Да", "Нет"}, "Нет", "Нет"},
+		{"number", []string{"Да", "Нет"}, "2", "Нет"},
+		{"number with hash", []string{"Да", "Нет"}, "#1", "Да"},
+		{"normalized text", []string{"Пицца с грибами"}, "  пицца  С грибами", "Пицца с грибами"},
+		{"numeric option by text", []string{"1", "2", "3", "5", "8"}, "5", "5"},
+		{"numeric options ignore bare number", []string{"1", "2", "3", "5", "8"}, "4", "4"},
+		{"numeric option by hash", []string{"1", "2", "3", "5", "8"}, "#4", "5"},
+		{"number out of range", []string{"Да", "Нет"}, "3", "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchOption(tt.options, tt.input); got != tt.want {
+				t.Errorf("matchOption(%v, %q) = %q, want %q", tt.options, tt.input, got, tt.want)
+			}
+		})
+	}
+}
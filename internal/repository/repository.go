@@ -1,24 +1,62 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/bllooop/votingbot/internal/domain"
 	"github.com/tarantool/go-tarantool/v2"
 )
 
 type Polls interface {
-	CreateDB(question string, options []string, creatorId string) (string, []string, error)
-	CastDB(pollID string, option string) error
-	GetRes(pollID string) (domain.Results, error)
+	CreateDB(poll domain.Poll) (string, []string, error)
+	CastDB(ballot domain.Ballot) ([]string, error)
+	RevoteDB(ballot domain.Ballot) ([]string, error)
+	RetractDB(pollID string, userID string) error
+	GetRes(pollID string) (domain.PollResults, error)
+	GetPollDB(pollID string) (domain.Poll, error)
+	VotersDB(pollID string) (domain.Poll, []domain.Ballot, error)
 	CloseDB(pollID string, creatorId string) error
+	CloseExpiredDB(now time.Time) ([]domain.Poll, error)
+	PublishDB(pollID string, creatorId string) (domain.Poll, error)
+	ReopenDB(pollID string, creatorId string, expiresAt int64) (domain.Poll, error)
+	ArchiveDB(pollID string, creatorId string) error
+	ApproveDB(pollID string, creatorId string, option string) error
+	EditDB(pollID string, creatorId string, edit domain.PollEdit) error
+	SetTieWinnerDB(pollID string, option string) error
+	SetRunoffDB(pollID string, expected string, runoffID string) (bool, error)
+	OpenScheduledDB(now time.Time) ([]domain.Poll, error)
+	DueRemindersDB(now time.Time) ([]domain.Poll, error)
+	MarkRemindedDB(pollID string) error
+	ListDB(channelID string, userID string, filter string, page int) (domain.PollPage, error)
+	MineDB(userID string, page int) (domain.PollPage, error)
+	SearchChannelsDB(query string, teamID string) ([]string, error)
+	SearchDB(query string, channelID string, teamID string, channels []string, userID string, page int) (domain.PollPage, error)
 	DeleteDB(pollID string, creatorId string) error
 }
 
+type Weights interface {
+	SetWeightDB(weight domain.Weight) error
+	RemoveWeightDB(scope string, userID string) error
+	ListWeightsDB(scope string) ([]domain.Weight, error)
+}
+
+type Templates interface {
+	SaveTemplateDB(template domain.Template) error
+	DeleteTemplateDB(ownerID string, name string) error
+	GetTemplateDB(ownerID string, name string) (domain.Template, error)
+	ListTemplatesDB(ownerID string) ([]domain.Template, error)
+}
+
 type Repository struct {
 	Polls
+	Weights
+	Templates
 }
 
 func NewRepository(db *tarantool.Connection) *Repository {
 	return &Repository{
-		Polls: NewPollsTarantool(db),
+		Polls:     NewPollsTarantool(db),
+		Weights:   NewWeightsTarantool(db),
+		Templates: NewTemplatesTarantool(db),
 	}
 }
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	logger "github.com/bllooop/votingbot/pkg/logging"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+const (
+	termFieldTerm = iota
+	termFieldPollID
+	termFieldChannelID
+	termFieldTeamID
+	termFieldWeight
+)
+
+const (
+	questionTermWeight = 2
+	optionTermWeight   = 1
+	minTermLength      = 2
+	termScanBatch      = 1000
+)
+
+// searchTerms разбивает текст на слова в нижнем регистре. Слишком короткие
+// слова и повторы отбрасываются.
+func searchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(word) >= minTermLength && !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// indexPoll заново записывает слова вопроса и вариантов голосования в
+// поисковый индекс. Слова вопроса весят больше слов вариантов.
+func (r *PollsTarantool) indexPoll(poll domain.Poll) error {
+	if err := r.unindexPoll(poll.ID); err != nil {
+		return err
+	}
+	weights := make(map[string]int)
+	for _, term := range searchTerms(poll.Question) {
+		weights[term] += questionTermWeight
+	}
+	for _, option := range poll.Options {
+		for _, term := range searchTerms(option) {
+			weights[term] += optionTermWeight
+		}
+	}
+	for term, weight := range weights {
+		_, err := r.db.Do(
+			tarantool.NewReplaceRequest("search_terms").
+				Tuple([]interface{}{term, poll.ID, poll.ChannelID, poll.TeamID, uint64(weight)}),
+		).Get()
+		if err != nil {
+			return err
+		}
+	}
+	logger.Log.Debug().Msgf("Голосование %s проиндексировано, слов: %d", poll.ID, len(weights))
+	return nil
+}
+
+func (r *PollsTarantool) unindexPoll(pollID string) error {
+	resp, err := r.db.Do(
+		tarantool.NewSelectRequest("search_terms").
+			Index("poll").
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{pollID}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	for _, rawRow := range resp {
+		row, ok := rawRow.([]interface{})
+		if !ok || len(row) <= termFieldPollID {
+			return fmt.Errorf("неожиданный формат данных: %v", rawRow)
+		}
+		_, err := r.db.Do(
+			tarantool.NewDeleteRequest("search_terms").
+				Key([]interface{}{row[termFieldTerm], row[termFieldPollID]}),
+		).Get()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateIndexed обновляет голосование и его слова в поисковом индексе.
+func (r *PollsTarantool) updateIndexed(poll domain.Poll, ops *tarantool.Operations) error {
+	if err := r.updatePoll(poll.ID, ops); err != nil {
+		return err
+	}
+	return r.indexPoll(poll)
+}
+
+// termMatch — слово голосования, которое начинается со слова запроса.
+type termMatch struct {
+	word      string
+	pollID    string
+	channelID string
+	weight    int
+}
+
+// scanTerms перебирает слова голосований канала или команды, начинающиеся с
+// term, порциями по termScanBatch, пока не закончатся подходящие слова.
+func (r *PollsTarantool) scanTerms(index string, scope string, term string, visit func(termMatch)) error {
+	scopeField := termFieldChannelID
+	if index == "team" {
+		scopeField = termFieldTeamID
+	}
+	key := []interface{}{scope, term}
+	iterator := tarantool.IterGe
+	for {
+		resp, err := r.db.Do(
+			tarantool.NewSelectRequest("search_terms").
+				Index(index).
+				Limit(termScanBatch).
+				Iterator(iterator).
+				Key(key),
+		).Get()
+		if err != nil {
+			return err
+		}
+		for _, rawRow := range resp {
+			row, ok := rawRow.([]interface{})
+			if !ok || len(row) <= termFieldWeight {
+				return fmt.Errorf("неожиданный формат данных: %v", rawRow)
+			}
+			var match termMatch
+			match.word, _ = row[termFieldTerm].(string)
+			match.pollID, _ = row[termFieldPollID].(string)
+			match.channelID, _ = row[termFieldChannelID].(string)
+			match.weight, _ = toInt(row[termFieldWeight])
+			rowScope, _ := row[scopeField].(string)
+			if rowScope != scope || !strings.HasPrefix(match.word, term) {
+				return nil
+			}
+			visit(match)
+			key = []interface{}{scope, match.word, match.pollID}
+		}
+		if len(resp) < termScanBatch {
+			return nil
+		}
+		iterator = tarantool.IterGt
+	}
+}
+
+// SearchChannelsDB возвращает каналы команды, в голосованиях которых есть
+// слова запроса.
+func (r *PollsTarantool) SearchChannelsDB(query string, teamID string) ([]string, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("запрос должен содержать хотя бы одно слово из %d и более букв", minTermLength)
+	}
+	var channels []string
+	for _, term := range terms {
+		err := r.scanTerms("team", teamID, term, func(match termMatch) {
+			if !slices.Contains(channels, match.channelID) {
+				channels = append(channels, match.channelID)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return channels, nil
+}
+
+// SearchDB ищет голосования канала или, если channelID пуст, команды по
+// словам запроса. В поиске по команде учитываются только голосования из
+// каналов channels. Слово запроса совпадает со словом голосования целиком
+// или как его начало; полное совпадение весит вдвое больше. Голосования
+// упорядочены по сумме весов совпавших слов.
+func (r *PollsTarantool) SearchDB(query string, channelID string, teamID string, channels []string, userID string, page int) (domain.PollPage, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return domain.PollPage{}, fmt.Errorf("запрос должен содержать хотя бы одно слово из %d и более букв", minTermLength)
+	}
+	if channelID == "" && teamID == "" {
+		return domain.PollPage{}, fmt.Errorf("не указан канал или команда для поиска")
+	}
+
+	index, scope := "channel", channelID
+	if channelID == "" {
+		index, scope = "team", teamID
+	}
+	scores := make(map[string]int)
+	matched := make(map[string]int)
+	for _, term := range terms {
+		seen := make(map[string]bool)
+		err := r.scanTerms(index, scope, term, func(match termMatch) {
+			if channelID == "" && !slices.Contains(channels, match.channelID) {
+				return
+			}
+			weight := match.weight
+			if match.word == term {
+				weight *= 2
+			}
+			scores[match.pollID] += weight
+			if !seen[match.pollID] {
+				seen[match.pollID] = true
+				matched[match.pollID]++
+			}
+		})
+		if err != nil {
+			return domain.PollPage{}, err
+		}
+	}
+
+	polls := make([]domain.Poll, 0, len(scores))
+	for pollID := range scores {
+		poll, err := r.getPollByID(pollID)
+		if err != nil {
+			return domain.PollPage{}, err
+		}
+		if poll.Status != domain.StatusDraft || poll.CreatorID == userID {
+			polls = append(polls, poll)
+		}
+	}
+	slices.SortFunc(polls, func(a, b domain.Poll) int {
+		return cmp.Or(
+			cmp.Compare(matched[b.ID], matched[a.ID]),
+			cmp.Compare(scores[b.ID], scores[a.ID]),
+			cmp.Compare(createdAt(b), createdAt(a)),
+		)
+	})
+	return r.pollPage(polls, page)
+}
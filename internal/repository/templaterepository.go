@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	logger "github.com/bllooop/votingbot/pkg/logging"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+const (
+	templateFieldOwnerID = iota
+	templateFieldName
+	templateFieldOptions
+	templateFieldKind
+	templateFieldMaxChoices
+	templateFieldBlind
+	templateFieldPublic
+	templateFieldQuorum
+	templateFieldThreshold
+	templateFieldTieBreak
+	templateFieldWriteIn
+)
+
+type TemplatesTarantool struct {
+	db *tarantool.Connection
+}
+
+func NewTemplatesTarantool(db *tarantool.Connection) *TemplatesTarantool {
+	return &TemplatesTarantool{
+		db: db,
+	}
+}
+
+func (r *TemplatesTarantool) SaveTemplateDB(template domain.Template) error {
+	data, err := r.db.Do(
+		tarantool.NewReplaceRequest("templates").
+			Tuple([]interface{}{
+				template.OwnerID,
+				template.Name,
+				template.Options,
+				template.Kind,
+				uint64(template.MaxChoices),
+				template.Blind,
+				template.Public,
+				uint64(template.Quorum),
+				uint64(template.Threshold),
+				template.TieBreak,
+				template.WriteIn,
+			}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Any("data", data).Msg("Сохранен шаблон голосования")
+	return nil
+}
+
+func (r *TemplatesTarantool) DeleteTemplateDB(ownerID string, name string) error {
+	data, err := r.db.Do(
+		tarantool.NewDeleteRequest("templates").
+			Key([]interface{}{ownerID, name}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("шаблон %s не найден", name)
+	}
+	logger.Log.Debug().Any("data", data).Msg("Удален шаблон голосования")
+	return nil
+}
+
+func (r *TemplatesTarantool) GetTemplateDB(ownerID string, name string) (domain.Template, error) {
+	templates, err := r.selectTemplates([]interface{}{ownerID, name})
+	if err != nil {
+		return domain.Template{}, err
+	}
+	if len(templates) == 0 {
+		return domain.Template{}, fmt.Errorf("шаблон %s не найден", name)
+	}
+	return templates[0], nil
+}
+
+func (r *TemplatesTarantool) ListTemplatesDB(ownerID string) ([]domain.Template, error) {
+	return r.selectTemplates([]interface{}{ownerID})
+}
+
+func (r *TemplatesTarantool) selectTemplates(key []interface{}) ([]domain.Template, error) {
+	resp, err := r.db.Do(
+		tarantool.NewSelectRequest("templates").
+			Iterator(tarantool.IterEq).
+			Key(key),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]domain.Template, 0, len(resp))
+	for _, rawRow := range resp {
+		row, ok := rawRow.([]interface{})
+		if !ok || len(row) <= templateFieldWriteIn {
+			return nil, fmt.Errorf("неожиданный формат данных: %v", rawRow)
+		}
+		options, ok := toStrings(row[templateFieldOptions])
+		if !ok {
+			return nil, fmt.Errorf("некорректный формат данных вариантов ответа")
+		}
+		template := domain.Template{Options: options}
+		template.OwnerID, _ = row[templateFieldOwnerID].(string)
+		template.Name, _ = row[templateFieldName].(string)
+		template.Kind, _ = row[templateFieldKind].(string)
+		template.MaxChoices, _ = toInt(row[templateFieldMaxChoices])
+		template.Blind, _ = row[templateFieldBlind].(bool)
+		template.Public, _ = row[templateFieldPublic].(bool)
+		template.Quorum, _ = toInt(row[templateFieldQuorum])
+		template.Threshold, _ = toInt(row[templateFieldThreshold])
+		template.TieBreak, _ = row[templateFieldTieBreak].(string)
+		template.WriteIn, _ = row[templateFieldWriteIn].(string)
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/bllooop/votingbot/internal/domain"
+)
+
+const (
+	pollFieldID = iota
+	pollFieldQuestion
+	pollFieldOptions
+	pollFieldCreatorID
+	pollFieldStatus
+	pollFieldMaxChoices
+	pollFieldKind
+	pollFieldChannelID
+	pollFieldExpiresAt
+	pollFieldOpensAt
+	pollFieldHistory
+	pollFieldBlind
+	pollFieldPublic
+	pollFieldQuorum
+	pollFieldThreshold
+	pollFieldTieBreak
+	pollFieldSeed
+	pollFieldTieWinner
+	pollFieldRunoffID
+	pollFieldWriteIn
+	pollFieldSuggestions
+	pollFieldOptionIDs
+	pollFieldNextOptionID
+	pollFieldShortID
+	pollFieldTeamID
+	pollFieldInvited
+	pollFieldRemindBefore
+	pollFieldReminded
+	pollFieldEligibleNames
+	pollFieldEligible
+	pollFieldRemindAt
+)
+
+const (
+	ballotFieldPollID = iota
+	ballotFieldUserID
+	ballotFieldOptions
+	ballotFieldCastAt
+	ballotFieldValues
+)
+
+// toInt приводит целое число из msgpack к int: Tarantool кодирует числа
+// минимальным по размеру типом, поэтому на входе может быть любой из них.
+func toInt(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case int8:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case int:
+		return v, true
+	case uint8:
+		return int(v), true
+	case uint16:
+		return int(v), true
+	case uint32:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	case uint:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func toInts(v interface{}) ([]int, bool) {
+	raw, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	result := make([]int, 0, len(raw))
+	for _, item := range raw {
+		n, ok := toInt(item)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, n)
+	}
+	return result, true
+}
+
+func toStrings(v interface{}) ([]string, bool) {
+	raw, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	result := make([]string, 0, len(raw))
+	for _, item := range raw {
+		str, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, str)
+	}
+	return result, true
+}
+
+func field(row []interface{}, i int) interface{} {
+	if i < len(row) {
+		return row[i]
+	}
+	return nil
+}
+
+func pollTuple(poll domain.Poll) []interface{} {
+	return []interface{}{
+		poll.ID,
+		poll.Question,
+		poll.Options,
+		poll.CreatorID,
+		poll.Status,
+		uint64(poll.MaxChoices),
+		poll.Kind,
+		poll.ChannelID,
+		uint64(poll.ExpiresAt),
+		uint64(poll.OpensAt),
+		historyTuple(poll.History),
+		poll.Blind,
+		poll.Public,
+		uint64(poll.Quorum),
+		uint64(poll.Threshold),
+		poll.TieBreak,
+		poll.Seed,
+		poll.TieWinner,
+		poll.RunoffID,
+		poll.WriteIn,
+		stringsTuple(poll.Suggestions),
+		poll.OptionIDs,
+		uint64(poll.NextOptionID),
+		poll.ShortID,
+		poll.TeamID,
+		stringsTuple(poll.Invited),
+		uint64(poll.RemindBefore),
+		poll.Reminded,
+		stringsTuple(poll.EligibleNames),
+		stringsTuple(poll.Eligible),
+		uint64(remindAt(poll)),
+	}
+}
+
+// remindAt возвращает время автоматического напоминания или 0, если
+// напоминание не задано или уже отправлено. Время хранится в поле remind_at,
+// чтобы выбирать готовые к напоминанию голосования по индексу.
+func remindAt(poll domain.Poll) int64 {
+	if poll.RemindBefore <= 0 || poll.ExpiresAt == 0 || poll.Reminded {
+		return 0
+	}
+	return max(poll.ExpiresAt-poll.RemindBefore, 1)
+}
+
+// stringsTuple заменяет nil пустым списком, чтобы поле-массив не
+// сохранялось в Tarantool как nil.
+func stringsTuple(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func historyTuple(history []domain.StatusChange) []interface{} {
+	result := make([]interface{}, 0, len(history))
+	for _, change := range history {
+		result = append(result, []interface{}{change.Status, uint64(change.At), change.UserID})
+	}
+	return result
+}
+
+func parseHistory(v interface{}) []domain.StatusChange {
+	raw, _ := v.([]interface{})
+	history := make([]domain.StatusChange, 0, len(raw))
+	for _, item := range raw {
+		entry, ok := item.([]interface{})
+		if !ok || len(entry) < 3 {
+			continue
+		}
+		change := domain.StatusChange{}
+		change.Status, _ = entry[0].(string)
+		at, _ := toInt(entry[1])
+		change.At = int64(at)
+		change.UserID, _ = entry[2].(string)
+		history = append(history, change)
+	}
+	return history
+}
+
+func parsePoll(row []interface{}) (domain.Poll, error) {
+	if len(row) <= pollFieldStatus {
+		return domain.Poll{}, fmt.Errorf("некорректный формат данных голосования")
+	}
+	options, ok := toStrings(row[pollFieldOptions])
+	if !ok {
+		return domain.Poll{}, fmt.Errorf("некорректный формат данных вариантов ответа")
+	}
+	poll := domain.Poll{Options: options}
+	poll.ID, _ = row[pollFieldID].(string)
+	poll.Question, _ = row[pollFieldQuestion].(string)
+	poll.CreatorID, _ = row[pollFieldCreatorID].(string)
+	poll.Status, _ = row[pollFieldStatus].(string)
+	poll.MaxChoices, ok = toInt(field(row, pollFieldMaxChoices))
+	if !ok || poll.MaxChoices < 1 {
+		poll.MaxChoices = 1
+	}
+	poll.Kind, _ = field(row, pollFieldKind).(string)
+	if poll.Kind == "" {
+		poll.Kind = domain.KindPlurality
+	}
+	poll.ChannelID, _ = field(row, pollFieldChannelID).(string)
+	expiresAt, _ := toInt(field(row, pollFieldExpiresAt))
+	poll.ExpiresAt = int64(expiresAt)
+	opensAt, _ := toInt(field(row, pollFieldOpensAt))
+	poll.OpensAt = int64(opensAt)
+	poll.History = parseHistory(field(row, pollFieldHistory))
+	poll.Blind, _ = field(row, pollFieldBlind).(bool)
+	poll.Public, _ = field(row, pollFieldPublic).(bool)
+	poll.Quorum, _ = toInt(field(row, pollFieldQuorum))
+	poll.Threshold, _ = toInt(field(row, pollFieldThreshold))
+	poll.TieBreak, _ = field(row, pollFieldTieBreak).(string)
+	seed, _ := toInt(field(row, pollFieldSeed))
+	poll.Seed = uint64(seed)
+	poll.TieWinner, _ = field(row, pollFieldTieWinner).(string)
+	poll.RunoffID, _ = field(row, pollFieldRunoffID).(string)
+	poll.WriteIn, _ = field(row, pollFieldWriteIn).(string)
+	poll.Suggestions, _ = toStrings(field(row, pollFieldSuggestions))
+	poll.OptionIDs, _ = toInts(field(row, pollFieldOptionIDs))
+	poll.NextOptionID, _ = toInt(field(row, pollFieldNextOptionID))
+	poll.ShortID, _ = field(row, pollFieldShortID).(string)
+	poll.TeamID, _ = field(row, pollFieldTeamID).(string)
+	poll.Invited, _ = toStrings(field(row, pollFieldInvited))
+	remindBefore, _ := toInt(field(row, pollFieldRemindBefore))
+	poll.RemindBefore = int64(remindBefore)
+	poll.Reminded, _ = field(row, pollFieldReminded).(bool)
+	poll.EligibleNames, _ = toStrings(field(row, pollFieldEligibleNames))
+	poll.Eligible, _ = toStrings(field(row, pollFieldEligible))
+	if len(poll.OptionIDs) != len(poll.Options) {
+		// Голосования, созданные до появления идентификаторов вариантов.
+		poll.OptionIDs = make([]int, len(poll.Options))
+		for i := range poll.Options {
+			poll.OptionIDs[i] = i + 1
+		}
+		poll.NextOptionID = len(poll.Options) + 1
+	}
+	return poll, nil
+}
+
+// ballotTuple сохраняет выбранные варианты по их идентификаторам в poll.
+func ballotTuple(poll domain.Poll, ballot domain.Ballot) []interface{} {
+	values := ballot.Values
+	if values == nil {
+		values = []int{}
+	}
+	return []interface{}{
+		ballot.PollID,
+		ballot.UserID,
+		optionIDs(poll, ballot.Options),
+		uint64(ballot.CastAt),
+		values,
+	}
+}
+
+// parseBallot восстанавливает названия выбранных вариантов по их
+// идентификаторам в poll. Удаленные варианты пропускаются вместе с их
+// оценками. Старые бюллетени хранят названия вариантов и читаются как есть.
+func parseBallot(poll domain.Poll, row []interface{}) (domain.Ballot, error) {
+	if len(row) <= ballotFieldCastAt {
+		return domain.Ballot{}, fmt.Errorf("некорректный формат данных голоса")
+	}
+	ballot := domain.Ballot{}
+	ballot.PollID, _ = row[ballotFieldPollID].(string)
+	ballot.UserID, _ = row[ballotFieldUserID].(string)
+	castAt, _ := toInt(row[ballotFieldCastAt])
+	ballot.CastAt = int64(castAt)
+	values, _ := toInts(field(row, ballotFieldValues))
+
+	options, ok := toStrings(row[ballotFieldOptions])
+	if !ok {
+		ids, ok := toInts(row[ballotFieldOptions])
+		if !ok {
+			return domain.Ballot{}, fmt.Errorf("некорректный формат данных голоса")
+		}
+		options = make([]string, len(ids))
+		for i, id := range ids {
+			if idx := slices.Index(poll.OptionIDs, id); idx >= 0 {
+				options[i] = poll.Options[idx]
+			}
+		}
+	}
+	for i, option := range options {
+		if !slices.Contains(poll.Options, option) {
+			continue
+		}
+		ballot.Options = append(ballot.Options, option)
+		if i < len(values) {
+			ballot.Values = append(ballot.Values, values[i])
+		}
+	}
+	return ballot, nil
+}
+
+func optionIDs(poll domain.Poll, options []string) []int {
+	ids := make([]int, 0, len(options))
+	for _, option := range options {
+		if idx := slices.Index(poll.Options, option); idx >= 0 {
+			ids = append(ids, poll.OptionIDs[idx])
+		}
+	}
+	return ids
+}
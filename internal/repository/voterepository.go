@@ -1,11 +1,19 @@
 package repository
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/bllooop/votingbot/internal/domain"
 	logger "github.com/bllooop/votingbot/pkg/logging"
 	"github.com/google/uuid"
+	"github.com/tarantool/go-iproto"
 	"github.com/tarantool/go-tarantool/v2"
 )
 
@@ -19,245 +27,1469 @@ func NewPollsTarantool(db *tarantool.Connection) *PollsTarantool {
 	}
 }
 
-func (r *PollsTarantool) CreateDB(question string, options []string, creatorId string) (string, []string, error) {
-	pollID := uuid.New().String()
-	votes := make([]int, len(options))
-	data, err := r.db.Do(
-		tarantool.NewInsertRequest("polls").Tuple([]interface{}{pollID, question, options, creatorId, votes, "active"})).Get()
+// CreateDB сохраняет голосование и возвращает его короткий ID вместе с
+// вариантами ответа.
+func (r *PollsTarantool) CreateDB(poll domain.Poll) (string, []string, error) {
+	if poll.MaxChoices == 0 {
+		poll.MaxChoices = 1
+	}
+	if err := domain.CheckSettings(poll); err != nil {
+		return "", nil, err
+	}
+	if poll.Kind == "" {
+		poll.Kind = domain.KindPlurality
+	}
+	if poll.Kind != domain.KindPlurality {
+		poll.MaxChoices = len(poll.Options)
+	}
+	if poll.TieBreak == domain.TieBreakRandom {
+		poll.Seed = rand.Uint64()
+	}
+	now := time.Now().Unix()
+	if poll.ExpiresAt != 0 && poll.ExpiresAt <= now {
+		return "", nil, fmt.Errorf("срок окончания голосования должен быть в будущем")
+	}
+	if poll.ExpiresAt != 0 && poll.ExpiresAt <= poll.OpensAt {
+		return "", nil, fmt.Errorf("срок окончания голосования должен быть позже его открытия")
+	}
+	if poll.RemindBefore < 0 || poll.RemindBefore > 0 && poll.ExpiresAt == 0 {
+		return "", nil, fmt.Errorf("автоматическое напоминание задается вместе со сроком окончания голосования")
+	}
+	if poll.Status != domain.StatusDraft {
+		poll.Status = initialStatus(poll, now)
+	}
+	poll.ID = uuid.New().String()
+	poll.OptionIDs = make([]int, len(poll.Options))
+	for i := range poll.Options {
+		poll.OptionIDs[i] = i + 1
+	}
+	poll.NextOptionID = len(poll.Options) + 1
+	poll.History = []domain.StatusChange{{Status: poll.Status, At: now, UserID: poll.CreatorID}}
+	var data []interface{}
+	var err error
+	for range shortIDAttempts {
+		poll.ShortID = newShortID()
+		data, err = r.db.Do(
+			tarantool.NewInsertRequest("polls").Tuple(pollTuple(poll))).Get()
+		var tntErr tarantool.Error
+		if !errors.As(err, &tntErr) || tntErr.Code != iproto.ER_TUPLE_FOUND {
+			break
+		}
+		logger.Log.Debug().Msgf("Короткий ID %s уже занят", poll.ShortID)
+	}
 	if err != nil {
 		return "", nil, err
 	}
 	if len(data) == 0 {
 		return "", nil, fmt.Errorf("ошибка добавления голосования")
 	}
+	if err := r.indexPoll(poll); err != nil {
+		return "", nil, err
+	}
 	logger.Log.Debug().Any("data", data).Msg("Создано голосование")
-	return pollID, options, nil
+	return poll.ShortID, poll.Options, nil
 }
 
-func (r *PollsTarantool) CastDB(pollID string, option string) error {
-	resp, err := r.db.Do(
-		tarantool.NewSelectRequest("polls").
-			Iterator(tarantool.IterEq).
-			Key([]interface{}{pollID}),
+const (
+	shortIDAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	shortIDLength   = 4
+	shortIDAttempts = 10
+)
+
+// newShortID генерирует короткий ID вида P-7K3F. В алфавите нет символов,
+// которые легко спутать: 0 и O, 1 и I.
+func newShortID() string {
+	id := make([]byte, shortIDLength)
+	for i := range id {
+		id[i] = shortIDAlphabet[rand.IntN(len(shortIDAlphabet))]
+	}
+	return domain.ShortIDPrefix + string(id)
+}
+
+func (r *PollsTarantool) CastDB(ballot domain.Ballot) ([]string, error) {
+	poll, err := r.getPollByID(ballot.PollID)
+	if err != nil {
+		return nil, err
+	}
+	ballot.PollID = poll.ID
+	if err := checkEligible(poll, ballot.UserID); err != nil {
+		return nil, err
+	}
+	// Голос проверяется до writeIns, чтобы повторный cast не добавлял
+	// варианты ответа.
+	voted, err := r.hasBallot(poll.ID, ballot.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if voted {
+		return nil, fmt.Errorf("%w: %s", domain.ErrAlreadyVoted, ballot.PollID)
+	}
+	ballot = resolveOptions(poll, ballot)
+	poll, err = r.writeIns(poll, ballot)
+	if err != nil {
+		return nil, err
+	}
+
+	ballot.CastAt = time.Now().Unix()
+	_, err = r.db.Do(
+		tarantool.NewInsertRequest("ballots").
+			Tuple(ballotTuple(poll, ballot)),
 	).Get()
 	if err != nil {
-		return err
+		var tntErr tarantool.Error
+		if errors.As(err, &tntErr) && tntErr.Code == iproto.ER_TUPLE_FOUND {
+			return nil, fmt.Errorf("%w: %s", domain.ErrAlreadyVoted, ballot.PollID)
+		}
+		return nil, err
 	}
-	if len(resp) == 0 {
-		return fmt.Errorf("голосование с ID %s не найдено", pollID)
+
+	logger.Log.Debug().Any("poll_id", ballot.PollID).Any("user_id", ballot.UserID).Any("options", ballot.Options).Msg("Голос отдан успешно")
+	return ballot.Options, nil
+}
+
+func (r *PollsTarantool) RevoteDB(ballot domain.Ballot) ([]string, error) {
+	poll, err := r.getPollByID(ballot.PollID)
+	if err != nil {
+		return nil, err
+	}
+	ballot.PollID = poll.ID
+	if err := checkEligible(poll, ballot.UserID); err != nil {
+		return nil, err
+	}
+	voted, err := r.hasBallot(poll.ID, ballot.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !voted {
+		return nil, fmt.Errorf("%w: %s", domain.ErrNotVoted, ballot.PollID)
+	}
+	ballot = resolveOptions(poll, ballot)
+	poll, err = r.writeIns(poll, ballot)
+	if err != nil {
+		return nil, err
 	}
 
-	pollData, ok := resp[0].([]interface{})
-	if !ok || len(pollData) < 5 {
-		return fmt.Errorf("некорректный формат данных")
+	ballot.CastAt = time.Now().Unix()
+	data, err := r.db.Do(
+		tarantool.NewUpdateRequest("ballots").
+			Key([]interface{}{ballot.PollID, ballot.UserID}).
+			Operations(revoteOperations(poll, ballot)),
+	).Get()
+	if err != nil {
+		return nil, err
 	}
-	status, _ := pollData[5].(string)
-	if status == "closed" {
-		return fmt.Errorf("голосование с ID %s уже закрыто", pollID)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: %s", domain.ErrNotVoted, ballot.PollID)
 	}
 
-	optionsInterface, ok1 := pollData[2].([]interface{})
-	if !ok1 {
-		return fmt.Errorf("некорректный формат данных вариантов ответа")
+	logger.Log.Debug().Any("poll_id", ballot.PollID).Any("user_id", ballot.UserID).Any("options", ballot.Options).Msg("Голос изменен")
+	return ballot.Options, nil
+}
+
+// revoteOperations переписывает варианты, время и оценки бюллетеня теми же
+// значениями, что ballotTuple: бюллетень без оценок получает пустой массив,
+// а не nil, который поле values не принимает.
+func revoteOperations(poll domain.Poll, ballot domain.Ballot) *tarantool.Operations {
+	tuple := ballotTuple(poll, ballot)
+	return tarantool.NewOperations().
+		Assign(ballotFieldOptions, tuple[ballotFieldOptions]).
+		Assign(ballotFieldCastAt, tuple[ballotFieldCastAt]).
+		Assign(ballotFieldValues, tuple[ballotFieldValues])
+}
+
+// writeIns проверяет бюллетень и обрабатывает варианты, которых нет в
+// голосовании. В режиме domain.WriteInAdd они сразу становятся новыми
+// вариантами ответа, в режиме domain.WriteInSuggest попадают в очередь
+// предложений создателю, а голос не принимается. Возвращает голосование с
+// учетом добавленных вариантов.
+func (r *PollsTarantool) writeIns(poll domain.Poll, ballot domain.Ballot) (domain.Poll, error) {
+	var unknown []string
+	for _, option := range ballot.Options {
+		if !slices.Contains(poll.Options, option) && !slices.Contains(unknown, option) {
+			unknown = append(unknown, option)
+		}
+	}
+	if len(unknown) == 0 || poll.WriteIn == "" {
+		return poll, checkBallot(poll, ballot)
+	}
+	if err := checkActive(poll); err != nil {
+		return poll, err
 	}
 
-	var options []string
-	for _, opt := range optionsInterface {
-		strOpt, ok := opt.(string)
-		if !ok {
-			return fmt.Errorf("некорректный формат данных вариантов ответа")
+	if poll.WriteIn == domain.WriteInSuggest {
+		_, err := r.db.Do(
+			tarantool.NewCallRequest("suggest_poll_options").Args([]interface{}{poll.ID, unknown}),
+		).Get()
+		if err != nil {
+			return poll, err
 		}
-		options = append(options, strOpt)
+		return poll, fmt.Errorf("%w: %s", domain.ErrSuggested, strings.Join(unknown, ", "))
 	}
 
-	votesInterface, ok2 := pollData[4].([]interface{})
-	if !ok2 {
-		return fmt.Errorf("некорректный формат данных голосоов")
+	written, err := r.changeOptions(poll.ID, func(poll domain.Poll) (domain.Poll, error) {
+		poll = addOption(poll, unknown...)
+		return poll, checkBallot(poll, ballot)
+	})
+	if err != nil {
+		return poll, err
 	}
+	return written, nil
+}
 
-	var votes []int
-	for _, v := range votesInterface {
-		switch v := v.(type) {
-		case int8:
-			votes = append(votes, int(v))
-		default:
-			return fmt.Errorf("некорректный формат данных счета голосов: %T", v)
-		}
+// ApproveDB переносит предложенный участником вариант в варианты ответа.
+func (r *PollsTarantool) ApproveDB(pollID string, creatorId string, option string) error {
+	poll, err := r.getPollByID(pollID)
+	if err != nil {
+		return err
+	}
+	if poll.CreatorID != creatorId {
+		return fmt.Errorf("%w: одобрять варианты может только создатель голосования", domain.ErrForbidden)
+	}
+	if poll.Status == domain.StatusClosed || poll.Status == domain.StatusArchived {
+		return fmt.Errorf("голосование с ID %s уже закрыто", pollID)
+	}
+	if !slices.Contains(poll.Suggestions, option) {
+		return fmt.Errorf("вариант %s не предлагался в голосовании %s", option, pollID)
+	}
+	if _, err := r.changeOptions(poll.ID, func(poll domain.Poll) (domain.Poll, error) {
+		return addOption(poll, option), nil
+	}); err != nil {
+		return err
+	}
+	// Очередь меняется процедурой, а не записью прочитанной копии, чтобы не
+	// потерять предложения, пришедшие во время одобрения.
+	_, err = r.db.Do(
+		tarantool.NewCallRequest("remove_poll_suggestion").Args([]interface{}{poll.ID, option}),
+	).Get()
+	return err
+}
+
+// EditDB меняет вопрос или варианты ответа голосования. Варианты сохраняют
+// свои идентификаторы, поэтому при переименовании голоса за них не теряются,
+// а голоса за удаленный вариант перестают учитываться.
+func (r *PollsTarantool) EditDB(pollID string, creatorId string, edit domain.PollEdit) error {
+	poll, err := r.getPollByID(pollID)
+	if err != nil {
+		return err
+	}
+	if poll.CreatorID != creatorId {
+		return fmt.Errorf("%w: изменять голосование может только его создатель", domain.ErrForbidden)
+	}
+	if poll.Status == domain.StatusClosed || poll.Status == domain.StatusArchived {
+		return fmt.Errorf("голосование с ID %s уже закрыто", pollID)
+	}
+	if edit.Value == "" && edit.Action != domain.EditRemove {
+		return fmt.Errorf("новое значение не может быть пустым")
+	}
+	if edit.Action == domain.EditQuestion {
+		poll.Question = edit.Value
+		return r.updateIndexed(poll, tarantool.NewOperations().Assign(pollFieldQuestion, edit.Value))
 	}
 
-	if len(votes) == 0 {
-		votes = make([]int, len(options))
+	if poll.Kind == domain.KindSchedule && (edit.Action == domain.EditAdd || edit.Action == domain.EditRename) {
+		if _, _, err := domain.ParseSlot(edit.Value, time.Local); err != nil {
+			return err
+		}
 	}
 
-	if len(options) != len(votes) {
-		logger.Log.Error().Msgf("Несовпадение вариантов ответа (%d) и голосов (%d) для голосования %s", len(options), len(votes), pollID)
-		return fmt.Errorf("несовпадение данных вариантов ответа и голосов")
+	_, err = r.changeOptions(poll.ID, func(poll domain.Poll) (domain.Poll, error) {
+		return editOptions(poll, edit)
+	})
+	return err
+}
+
+// editOptions применяет к вариантам ответа добавление, удаление или
+// переименование.
+func editOptions(poll domain.Poll, edit domain.PollEdit) (domain.Poll, error) {
+	idx := slices.Index(poll.Options, edit.Option)
+	switch edit.Action {
+	case domain.EditAdd:
+		if slices.Contains(poll.Options, edit.Value) {
+			return poll, fmt.Errorf("вариант ответа %s уже есть в голосовании", edit.Value)
+		}
+		poll = addOption(poll, edit.Value)
+	case domain.EditRemove:
+		if idx < 0 {
+			return poll, fmt.Errorf("вариант ответа %s не найден в голосовании", edit.Option)
+		}
+		if len(poll.Options) <= 2 {
+			return poll, fmt.Errorf("в голосовании должно остаться хотя бы два варианта ответа")
+		}
+		poll.Options = slices.Delete(slices.Clone(poll.Options), idx, idx+1)
+		poll.OptionIDs = slices.Delete(slices.Clone(poll.OptionIDs), idx, idx+1)
+		poll.MaxChoices = min(poll.MaxChoices, len(poll.Options))
+	case domain.EditRename:
+		if idx < 0 {
+			return poll, fmt.Errorf("вариант ответа %s не найден в голосовании", edit.Option)
+		}
+		if slices.Contains(poll.Options, edit.Value) {
+			return poll, fmt.Errorf("вариант ответа %s уже есть в голосовании", edit.Value)
+		}
+		poll.Options = slices.Clone(poll.Options)
+		poll.Options[idx] = edit.Value
+	default:
+		return poll, fmt.Errorf("неизвестное изменение %s", edit.Action)
 	}
+	return poll, nil
+}
 
-	var optionIndex int = -1
-	for i, opt := range options {
-		if opt == option {
-			optionIndex = i
-			break
+// addOption добавляет варианты ответа с новыми идентификаторами. Варианты,
+// которые уже есть в голосовании, пропускаются. В ранжированном и оценочном
+// голосовании участник может отметить все варианты.
+func addOption(poll domain.Poll, options ...string) domain.Poll {
+	poll.Options = slices.Clone(poll.Options)
+	poll.OptionIDs = slices.Clone(poll.OptionIDs)
+	for _, option := range options {
+		if slices.Contains(poll.Options, option) {
+			continue
 		}
+		poll.Options = append(poll.Options, option)
+		poll.OptionIDs = append(poll.OptionIDs, poll.NextOptionID)
+		poll.NextOptionID++
 	}
-	if optionIndex == -1 {
-		return fmt.Errorf("вариант ответа %s не найден в голосовании", option)
+	if poll.Kind != domain.KindPlurality {
+		poll.MaxChoices = len(poll.Options)
 	}
+	return poll
+}
 
-	votes[optionIndex]++
+const optionsAttempts = 5
 
-	_, err = r.db.Do(
+// changeOptions применяет change к свежей копии голосования и сохраняет
+// варианты ответа через процедуру update_poll_options, которая отказывает,
+// если варианты успели изменить с момента чтения. Тогда изменение
+// повторяется на новых данных: параллельные изменения не затирают друг
+// друга и не выдают один идентификатор двум вариантам.
+func (r *PollsTarantool) changeOptions(pollID string, change func(domain.Poll) (domain.Poll, error)) (domain.Poll, error) {
+	for range optionsAttempts {
+		read, err := r.getPollByID(pollID)
+		if err != nil {
+			return domain.Poll{}, err
+		}
+		poll, err := change(read)
+		if err != nil {
+			return domain.Poll{}, err
+		}
+		resp, err := r.db.Do(
+			tarantool.NewCallRequest("update_poll_options").
+				Args([]interface{}{
+					poll.ID,
+					stringsTuple(read.Options), read.OptionIDs,
+					stringsTuple(poll.Options), poll.OptionIDs,
+					uint64(poll.NextOptionID), uint64(poll.MaxChoices),
+				}),
+		).Get()
+		if err != nil {
+			return domain.Poll{}, err
+		}
+		if saved, _ := field(resp, 0).(bool); saved {
+			return poll, r.indexPoll(poll)
+		}
+		logger.Log.Debug().Msgf("Варианты голосования %s изменились во время записи, повтор", pollID)
+	}
+	return domain.Poll{}, fmt.Errorf("варианты ответа голосования %s сейчас изменяются, повторите попытку", pollID)
+}
+
+func (r *PollsTarantool) updatePoll(pollID string, ops *tarantool.Operations) error {
+	data, err := r.db.Do(
 		tarantool.NewUpdateRequest("polls").
 			Key([]interface{}{pollID}).
-			Operations(tarantool.NewOperations().Assign(4, votes)),
+			Operations(ops),
 	).Get()
 	if err != nil {
 		return err
 	}
-
-	logger.Log.Debug().Any("poll_id", pollID).Any("option", option).Msg("Голос отдан успешно")
+	logger.Log.Debug().Any("data", data).Msg("Голосование обновлено")
 	return nil
 }
-func (r *PollsTarantool) GetRes(pollID string) (domain.Results, error) {
+
+func (r *PollsTarantool) RetractDB(pollID string, userID string) error {
+	poll, err := r.getPollByID(pollID)
+	if err != nil {
+		return err
+	}
+	if err := checkActive(poll); err != nil {
+		return err
+	}
+
 	data, err := r.db.Do(
-		tarantool.NewSelectRequest("polls").
-			Limit(1).
-			Iterator(tarantool.IterEq).
-			Key([]interface{}{pollID}),
+		tarantool.NewDeleteRequest("ballots").
+			Key([]interface{}{poll.ID, userID}),
 	).Get()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if len(data) == 0 {
-		return nil, fmt.Errorf("голосование с ID %s не найдено", pollID)
+		return fmt.Errorf("%w: %s", domain.ErrNotVoted, pollID)
 	}
 
-	var results domain.Results
-	for _, rawRow := range data {
+	logger.Log.Debug().Any("poll_id", pollID).Any("user_id", userID).Msg("Голос отозван")
+	return nil
+}
+
+func (r *PollsTarantool) GetRes(pollID string) (domain.PollResults, error) {
+	poll, err := r.getPollByID(pollID)
+	if err != nil {
+		return domain.PollResults{}, err
+	}
+	logger.Log.Debug().Msgf("Значения: id=%s, question=%s, options=%v, creator_id=%s, active=%s",
+		poll.ID, poll.Question, poll.Options, poll.CreatorID, poll.Status)
 
-		row, ok := rawRow.([]interface{})
-		if !ok || len(row) < 6 {
-			return nil, fmt.Errorf("неожиданный формат данных: %v", rawRow)
+	ballots, err := r.getBallots(poll)
+	if err != nil {
+		return domain.PollResults{}, err
+	}
+	weights, err := voterWeights(r.db, poll)
+	if err != nil {
+		return domain.PollResults{}, err
+	}
+	counts := make(map[string]int, len(poll.Options))
+	weighted := make(map[string]int, len(poll.Options))
+	for i, ballot := range ballots {
+		ballots[i].Weight = domain.MinWeight
+		if weight, ok := weights[ballot.UserID]; ok {
+			ballots[i].Weight = weight
+		}
+		chosen := ballot.Options
+		if domain.IsRanked(poll.Kind) {
+			chosen = ballot.Options[:1]
+		}
+		for j, option := range chosen {
+			if poll.Kind == domain.KindSchedule && j < len(ballot.Values) && ballot.Values[j] == domain.AvailabilityNo {
+				continue
+			}
+			counts[option]++
+			weighted[option] += ballots[i].Weight
 		}
+	}
 
-		pollID, _ := row[0].(string)
-		question, _ := row[1].(string)
-		optionsRaw, okOptions := row[2].([]interface{})
-		creatorID, _ := row[3].(string)
-		votesRaw, okVotes := row[4].([]interface{})
-		active, _ := row[5].(string)
+	results := domain.PollResults{
+		PollID:     poll.ID,
+		ShortID:    poll.ShortID,
+		Question:   poll.Question,
+		Kind:       poll.Kind,
+		Status:     poll.Status,
+		History:    poll.History,
+		MaxChoices: poll.MaxChoices,
+		CreatorID:  poll.CreatorID,
+		Blind:      poll.Blind,
+		Voters:     len(ballots),
+		Electorate: len(poll.Eligible),
+		Weighted:   len(weights) > 0,
+		TieBreak:   poll.TieBreak,
+		Seed:       poll.Seed,
+		RunoffID:   poll.RunoffID,
+		WriteIn:    poll.WriteIn,
+		Suggested:  poll.Suggestions,
+	}
+	if poll.ExpiresAt > 0 {
+		results.ExpiresAt = time.Unix(poll.ExpiresAt, 0).UTC().Format(time.RFC3339)
+	}
+	for _, option := range poll.Options {
+		results.Options = append(results.Options, domain.Result{
+			Question:  poll.Question,
+			Option:    option,
+			Count:     counts[option],
+			Weighted:  weighted[option],
+			ExpiresAt: results.ExpiresAt,
+		})
+	}
+	switch poll.Kind {
+	case domain.KindRanked:
+		results.Rounds, results.Winner = instantRunoff(poll, ballots)
+	case domain.KindSchulze:
+		results.Pairwise, results.Paths = schulze(poll, ballots)
+	case domain.KindScore:
+		results.Scores = scoreResults(poll, ballots)
+	case domain.KindSchedule:
+		results.Slots = scheduleResults(poll, ballots)
+	}
+	if best := leaders(poll, results); len(best) == 1 {
+		results.Winner = best[0]
+	} else if len(best) > 1 {
+		results.Winner = ""
+		results.Tied = best
+		if poll.Status == domain.StatusClosed || poll.Status == domain.StatusArchived {
+			results.Winner = breakTie(poll, best, ballots)
+		}
+	}
+	if poll.Quorum > 0 || poll.Threshold > 0 {
+		results.Decision = decide(poll, results, ballots)
+	}
 
-		logger.Log.Debug().Msgf("Значения: id=%s, question=%s, options=%v, creator_id=%s, votes=%v, active=%s",
-			pollID, question, optionsRaw, creatorID, votesRaw, active)
+	logger.Log.Debug().Any("data", results).Msg("Получены данные о голосовании")
+	return results, nil
+}
 
-		var options []string
-		if okOptions {
-			for _, opt := range optionsRaw {
-				if strOpt, ok := opt.(string); ok {
-					options = append(options, strOpt)
+// instantRunoff подсчитывает ранжированные бюллетени по правилам
+// мгновенного второго тура. В каждом раунде голос бюллетеня достается его
+// высшему предпочтению среди оставшихся вариантов; если никто не набрал
+// больше половины, выбывает вариант с наименьшим числом голосов. Варианты,
+// которые делят последнее место, выбывают вместе, только если их голосов в
+// сумме меньше, чем у следующего варианта, — иначе выбывает один из них по
+// eliminationOrder. Если наименьшее число голосов у всех оставшихся
+// вариантов, победитель не определяется.
+func instantRunoff(poll domain.Poll, ballots []domain.Ballot) ([]domain.Round, string) {
+	remaining := slices.Clone(poll.Options)
+	var rounds []domain.Round
+	for len(remaining) > 0 {
+		counts := make(map[string]int, len(remaining))
+		total := 0
+		for _, ballot := range ballots {
+			for _, option := range ballot.Options {
+				if slices.Contains(remaining, option) {
+					counts[option] += ballotWeight(ballot)
+					total += ballotWeight(ballot)
+					break
 				}
 			}
 		}
 
-		var votes []int
-		if okVotes {
-			for _, v := range votesRaw {
-				switch v := v.(type) {
-				case int8:
-					votes = append(votes, int(v))
-				default:
-					logger.Log.Error().Msgf("Некорректный тип голосов: %T, значение: %v", v, v)
-					return nil, fmt.Errorf("некорректный тип голосов: %T", v)
+		round := domain.Round{Number: len(rounds) + 1}
+		minCount := -1
+		for _, option := range remaining {
+			round.Counts = append(round.Counts, domain.Result{
+				Question: poll.Question,
+				Option:   option,
+				Count:    counts[option],
+			})
+			if minCount == -1 || counts[option] < minCount {
+				minCount = counts[option]
+			}
+		}
+
+		for _, option := range remaining {
+			if total > 0 && counts[option]*2 > total || len(remaining) == 1 {
+				return append(rounds, round), option
+			}
+		}
+
+		var survivors, lowest []string
+		nextCount := -1
+		for _, option := range remaining {
+			if counts[option] == minCount {
+				lowest = append(lowest, option)
+				continue
+			}
+			survivors = append(survivors, option)
+			if nextCount == -1 || counts[option] < nextCount {
+				nextCount = counts[option]
+			}
+		}
+		if len(survivors) == 0 {
+			return append(rounds, round), ""
+		}
+		eliminated := lowest
+		if len(lowest) > 1 && minCount*len(lowest) >= nextCount {
+			eliminated = []string{eliminationOrder(poll, rounds, lowest)}
+		}
+		round.Eliminated = eliminated
+		rounds = append(rounds, round)
+		remaining = slices.DeleteFunc(remaining, func(option string) bool {
+			return slices.Contains(eliminated, option)
+		})
+	}
+	return rounds, ""
+}
+
+// eliminationOrder выбирает, какой из вариантов, разделивших последнее
+// место, выбывает: тот, у кого меньше голосов в ближайшем предыдущем раунде,
+// где они различались, а если таких раундов нет — добавленный позже.
+func eliminationOrder(poll domain.Poll, rounds []domain.Round, tied []string) string {
+	for i := len(rounds) - 1; i >= 0 && len(tied) > 1; i-- {
+		counts := make(map[string]int, len(rounds[i].Counts))
+		for _, res := range rounds[i].Counts {
+			counts[res.Option] = res.Count
+		}
+		fewest := counts[tied[0]]
+		for _, option := range tied[1:] {
+			fewest = min(fewest, counts[option])
+		}
+		tied = slices.DeleteFunc(slices.Clone(tied), func(option string) bool {
+			return counts[option] != fewest
+		})
+	}
+	return slices.MaxFunc(tied, func(a, b string) int {
+		return cmp.Compare(slices.Index(poll.Options, a), slices.Index(poll.Options, b))
+	})
+}
+
+// schulze строит матрицу попарных предпочтений по ранжированным бюллетеням и
+// матрицу сильнейших путей между вариантами. pairwise[i][j] — суммарный вес
+// бюллетеней, в которых вариант i стоит выше варианта j; варианты, не
+// указанные в бюллетене, считаются ниже указанных и равными между собой.
+func schulze(poll domain.Poll, ballots []domain.Ballot) ([][]int, [][]int) {
+	n := len(poll.Options)
+	pairwise := make([][]int, n)
+	for i := range pairwise {
+		pairwise[i] = make([]int, n)
+	}
+	for _, ballot := range ballots {
+		rank := make(map[string]int, len(ballot.Options))
+		for i, option := range ballot.Options {
+			rank[option] = i + 1
+		}
+		for i, a := range poll.Options {
+			for j, b := range poll.Options {
+				rankA, okA := rank[a]
+				rankB, okB := rank[b]
+				if okA && (!okB || rankA < rankB) {
+					pairwise[i][j] += ballotWeight(ballot)
 				}
 			}
 		}
+	}
 
-		if len(votes) < len(options) {
-			for len(votes) < len(options) {
-				votes = append(votes, 0)
+	paths := make([][]int, n)
+	for i := range paths {
+		paths[i] = make([]int, n)
+		for j := range paths[i] {
+			if i != j && pairwise[i][j] > pairwise[j][i] {
+				paths[i][j] = pairwise[i][j]
+			}
+		}
+	}
+	for k := range n {
+		for i := range n {
+			if i == k {
+				continue
+			}
+			for j := range n {
+				if j != i && j != k {
+					paths[i][j] = max(paths[i][j], min(paths[i][k], paths[k][j]))
+				}
 			}
 		}
+	}
+	return pairwise, paths
+}
 
-		for i, option := range options {
-			results = append(results, domain.Result{
-				Question: question,
-				Option:   option,
-				Count:    votes[i],
-			})
+// scoreResults собирает для каждого варианта оценочного голосования среднее,
+// медиану, число оценок и распределение оценок от domain.MinScore до
+// domain.MaxScore. Оценка участника с весом N учитывается N раз.
+func scoreResults(poll domain.Poll, ballots []domain.Ballot) []domain.ScoreResult {
+	scores := make(map[string][]int, len(poll.Options))
+	counts := make(map[string]int, len(poll.Options))
+	for _, ballot := range ballots {
+		for i, option := range ballot.Options {
+			if i >= len(ballot.Values) {
+				continue
+			}
+			counts[option]++
+			for range ballotWeight(ballot) {
+				scores[option] = append(scores[option], ballot.Values[i])
+			}
 		}
 	}
 
-	logger.Log.Debug().Any("data", results).Msg("Получены данные о голосовании")
-	return results, nil
+	results := make([]domain.ScoreResult, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		values := scores[option]
+		result := domain.ScoreResult{
+			Option:    option,
+			Count:     counts[option],
+			Histogram: make([]int, domain.MaxScore-domain.MinScore+1),
+		}
+		if len(values) > 0 {
+			slices.Sort(values)
+			sum := 0
+			for _, value := range values {
+				sum += value
+				result.Histogram[value-domain.MinScore]++
+			}
+			result.Mean = float64(sum) / float64(len(values))
+			middle := len(values) / 2
+			if len(values)%2 == 0 {
+				result.Median = float64(values[middle-1]+values[middle]) / 2
+			} else {
+				result.Median = float64(values[middle])
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// scheduleResults считает для каждого слота, сколько участников он устраивает,
+// устраивает возможно или не устраивает. Неотмеченный слот не устраивает
+// участника, голос участника с весом N учитывается N раз.
+func scheduleResults(poll domain.Poll, ballots []domain.Ballot) []domain.SlotResult {
+	results := make([]domain.SlotResult, len(poll.Options))
+	for i, option := range poll.Options {
+		results[i].Option = option
+	}
+	for _, ballot := range ballots {
+		for i := range results {
+			availability := domain.AvailabilityNo
+			if j := slices.Index(ballot.Options, results[i].Option); j >= 0 && j < len(ballot.Values) {
+				availability = ballot.Values[j]
+			}
+			switch availability {
+			case domain.AvailabilityYes:
+				results[i].Yes += ballotWeight(ballot)
+			case domain.AvailabilityMaybe:
+				results[i].Maybe += ballotWeight(ballot)
+			default:
+				results[i].No += ballotWeight(ballot)
+			}
+		}
+	}
+	return results
+}
+
+func (r *PollsTarantool) GetPollDB(pollID string) (domain.Poll, error) {
+	return r.getPollByID(pollID)
+}
+
+func (r *PollsTarantool) VotersDB(pollID string) (domain.Poll, []domain.Ballot, error) {
+	poll, err := r.getPollByID(pollID)
+	if err != nil {
+		return domain.Poll{}, nil, err
+	}
+	ballots, err := r.getBallots(poll)
+	if err != nil {
+		return domain.Poll{}, nil, err
+	}
+	return poll, ballots, nil
+}
+
+// decide проверяет кворум и порог принятия решения для победителя
+// голосования. Доля победителя считается от суммарного веса проголосовавших,
+// а в ранжированном голосовании — от голосов последнего раунда.
+func decide(poll domain.Poll, results domain.PollResults, ballots []domain.Ballot) *domain.Decision {
+	decision := &domain.Decision{
+		Quorum:    poll.Quorum,
+		Threshold: poll.Threshold,
+		QuorumMet: len(ballots) >= poll.Quorum,
+		Leader:    results.Winner,
+	}
+
+	var leaderVotes, total int
+	switch poll.Kind {
+	case domain.KindRanked:
+		if len(results.Rounds) > 0 {
+			for _, res := range results.Rounds[len(results.Rounds)-1].Counts {
+				total += res.Count
+				if res.Option == results.Winner {
+					leaderVotes = res.Count
+				}
+			}
+		}
+	case domain.KindScore, domain.KindSchulze, domain.KindSchedule:
+	default:
+		for _, ballot := range ballots {
+			total += ballotWeight(ballot)
+		}
+		for _, res := range results.Options {
+			if res.Option == results.Winner {
+				leaderVotes = res.Weighted
+			}
+		}
+	}
+
+	if total > 0 {
+		decision.Share = float64(leaderVotes) * 100 / float64(total)
+	}
+	decision.Passed = decision.QuorumMet && decision.Leader != "" &&
+		(poll.Threshold == 0 || decision.Share >= float64(poll.Threshold))
+	return decision
+}
+
+// leaders возвращает варианты, которые делят первое место.
+func leaders(poll domain.Poll, results domain.PollResults) []string {
+	var best []string
+	switch poll.Kind {
+	case domain.KindRanked:
+		if results.Winner != "" {
+			return []string{results.Winner}
+		}
+		if len(results.Rounds) == 0 {
+			return nil
+		}
+		for _, res := range results.Rounds[len(results.Rounds)-1].Counts {
+			if res.Count > 0 {
+				best = append(best, res.Option)
+			}
+		}
+	case domain.KindSchulze:
+		if results.Voters == 0 || len(results.Paths) != len(poll.Options) {
+			return nil
+		}
+		for i, option := range poll.Options {
+			beaten := false
+			for j := range poll.Options {
+				if results.Paths[j][i] > results.Paths[i][j] {
+					beaten = true
+					break
+				}
+			}
+			if !beaten {
+				best = append(best, option)
+			}
+		}
+	case domain.KindScore:
+		bestMean := 0.0
+		for _, score := range results.Scores {
+			switch {
+			case score.Count == 0 || score.Mean < bestMean:
+			case score.Mean > bestMean:
+				bestMean, best = score.Mean, []string{score.Option}
+			default:
+				best = append(best, score.Option)
+			}
+		}
+	case domain.KindSchedule:
+		var bestYes, bestMaybe int
+		for _, slot := range results.Slots {
+			switch {
+			case slot.Yes+slot.Maybe == 0 || slot.Yes < bestYes || slot.Yes == bestYes && slot.Maybe < bestMaybe:
+			case slot.Yes > bestYes || slot.Maybe > bestMaybe:
+				bestYes, bestMaybe, best = slot.Yes, slot.Maybe, []string{slot.Option}
+			default:
+				best = append(best, slot.Option)
+			}
+		}
+	default:
+		bestCount := 0
+		for _, res := range results.Options {
+			switch {
+			case res.Weighted == 0 || res.Weighted < bestCount:
+			case res.Weighted > bestCount:
+				bestCount, best = res.Weighted, []string{res.Option}
+			default:
+				best = append(best, res.Option)
+			}
+		}
+	}
+	return best
+}
+
+// breakTie выбирает победителя среди вариантов с равным результатом по
+// правилу голосования. Пустая строка означает, что победителя пока нет:
+// создатель еще не выбрал его или назначен второй тур.
+func breakTie(poll domain.Poll, tied []string, ballots []domain.Ballot) string {
+	switch poll.TieBreak {
+	case domain.TieBreakCreator:
+		if slices.Contains(tied, poll.TieWinner) {
+			return poll.TieWinner
+		}
+	case domain.TieBreakRandom:
+		return tied[rand.New(rand.NewPCG(poll.Seed, 0)).IntN(len(tied))]
+	case domain.TieBreakEarliest:
+		return earliestLeader(poll, tied, ballots)
+	}
+	return ""
+}
+
+// earliestLeader выбирает вариант, который раньше остальных набрал свой
+// итоговый результат, то есть чей последний поддержавший бюллетень отдан
+// раньше. В ранжированном голосовании бюллетень поддерживает тот из
+// вариантов, который стоит в нем выше.
+func earliestLeader(poll domain.Poll, tied []string, ballots []domain.Ballot) string {
+	reachedAt := make(map[string]int64, len(tied))
+	for _, ballot := range ballots {
+		for i, option := range ballot.Options {
+			if !slices.Contains(tied, option) {
+				continue
+			}
+			if poll.Kind == domain.KindSchedule && i < len(ballot.Values) && ballot.Values[i] != domain.AvailabilityYes {
+				continue
+			}
+			reachedAt[option] = max(reachedAt[option], ballot.CastAt)
+			if domain.IsRanked(poll.Kind) {
+				break
+			}
+		}
+	}
+	winner := tied[0]
+	for _, option := range tied[1:] {
+		if reachedAt[option] < reachedAt[winner] {
+			winner = option
+		}
+	}
+	return winner
+}
+
+func ballotWeight(ballot domain.Ballot) int {
+	if ballot.Weight < domain.MinWeight {
+		return domain.MinWeight
+	}
+	return ballot.Weight
 }
 
 func (r *PollsTarantool) CloseDB(pollID string, creatorId string) error {
-	pollData, err := r.getPollByID(pollID)
+	poll, err := r.getPollByID(pollID)
 	if err != nil {
 		return err
 	}
-	creatorID, ok := pollData[3].(string)
-	if !ok || creatorID != creatorId {
-		return fmt.Errorf("только создатель может закрыть голосование")
+	if poll.CreatorID != creatorId {
+		return fmt.Errorf("%w: только создатель может закрыть голосование", domain.ErrForbidden)
 	}
-	status, _ := pollData[5].(string)
-	if status == "closed" {
+	if poll.Status == domain.StatusClosed || poll.Status == domain.StatusArchived {
 		return fmt.Errorf("голосование с ID %s уже закрыто", pollID)
 	}
+	return r.setStatus(poll, domain.StatusClosed, creatorId)
+}
 
+func (r *PollsTarantool) PublishDB(pollID string, creatorId string) (domain.Poll, error) {
+	poll, err := r.getPollByID(pollID)
+	if err != nil {
+		return domain.Poll{}, err
+	}
+	if poll.CreatorID != creatorId {
+		return domain.Poll{}, fmt.Errorf("%w: только создатель может опубликовать голосование", domain.ErrForbidden)
+	}
+	if poll.Status != domain.StatusDraft {
+		return domain.Poll{}, fmt.Errorf("голосование %s не является черновиком", pollID)
+	}
+	now := time.Now().Unix()
+	if poll.ExpiresAt != 0 && poll.ExpiresAt <= now {
+		return domain.Poll{}, fmt.Errorf("срок окончания голосования %s уже прошел", pollID)
+	}
+	status := initialStatus(poll, now)
+	if err := r.setStatus(poll, status, creatorId); err != nil {
+		return domain.Poll{}, err
+	}
+	poll.Status = status
+	return poll, nil
+}
+
+func (r *PollsTarantool) ReopenDB(pollID string, creatorId string, expiresAt int64) (domain.Poll, error) {
+	poll, err := r.getPollByID(pollID)
+	if err != nil {
+		return domain.Poll{}, err
+	}
+	if poll.CreatorID != creatorId {
+		return domain.Poll{}, fmt.Errorf("%w: только создатель может заново открыть голосование", domain.ErrForbidden)
+	}
+	if poll.Status != domain.StatusClosed {
+		return domain.Poll{}, fmt.Errorf("заново открыть можно только закрытое голосование")
+	}
+	now := time.Now().Unix()
+	switch {
+	case expiresAt != 0 && expiresAt <= now:
+		return domain.Poll{}, fmt.Errorf("срок окончания голосования должен быть в будущем")
+	case expiresAt != 0:
+		poll.ExpiresAt = expiresAt
+	case poll.ExpiresAt <= now:
+		poll.ExpiresAt = 0
+	}
+
+	ops, err := statusOperations(poll, domain.StatusActive, creatorId)
+	if err != nil {
+		return domain.Poll{}, err
+	}
+	// Ничья и второй тур прошлого закрытия к новым итогам не относятся, а
+	// автоматическое напоминание отправляется заново к новому сроку.
+	poll.TieWinner, poll.RunoffID, poll.Reminded = "", "", false
+	ops = ops.Assign(pollFieldExpiresAt, uint64(poll.ExpiresAt)).
+		Assign(pollFieldTieWinner, "").
+		Assign(pollFieldRunoffID, "").
+		Assign(pollFieldReminded, false).
+		Assign(pollFieldRemindAt, uint64(remindAt(poll)))
+	if err := r.updateStatus(poll.ID, domain.StatusActive, ops); err != nil {
+		return domain.Poll{}, err
+	}
+	poll.Status = domain.StatusActive
+	return poll, nil
+}
+
+func (r *PollsTarantool) SetTieWinnerDB(pollID string, option string) error {
 	data, err := r.db.Do(
 		tarantool.NewUpdateRequest("polls").
 			Key([]interface{}{pollID}).
-			Operations(tarantool.NewOperations().Assign(5, "closed")),
+			Operations(tarantool.NewOperations().Assign(pollFieldTieWinner, option)),
 	).Get()
 	if err != nil {
 		return err
 	}
-	logger.Log.Debug().Any("data", data).Msg("Закрыто голосование")
+	logger.Log.Debug().Any("data", data).Msg("Создатель разрешил ничью")
 	return nil
 }
+
+// SetRunoffDB записывает второй тур голосования через процедуру
+// set_poll_runoff, только если поле runoff_id все еще равно expected.
+// Возвращает false, если второй тур уже назначен другим вызовом.
+func (r *PollsTarantool) SetRunoffDB(pollID string, expected string, runoffID string) (bool, error) {
+	resp, err := r.db.Do(
+		tarantool.NewCallRequest("set_poll_runoff").Args([]interface{}{pollID, expected, runoffID}),
+	).Get()
+	if err != nil {
+		return false, err
+	}
+	saved, _ := field(resp, 0).(bool)
+	if saved {
+		logger.Log.Debug().Msgf("Голосованию %s назначен второй тур %s", pollID, runoffID)
+	}
+	return saved, nil
+}
+
+func (r *PollsTarantool) ArchiveDB(pollID string, creatorId string) error {
+	poll, err := r.getPollByID(pollID)
+	if err != nil {
+		return err
+	}
+	if poll.CreatorID != creatorId {
+		return fmt.Errorf("%w: только создатель может отправить голосование в архив", domain.ErrForbidden)
+	}
+	return r.setStatus(poll, domain.StatusArchived, creatorId)
+}
+
+func (r *PollsTarantool) OpenScheduledDB(now time.Time) ([]domain.Poll, error) {
+	polls, err := r.selectPolls("opens", tarantool.IterGe, []interface{}{domain.StatusScheduled, uint64(0)}, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	var opened []domain.Poll
+	for _, poll := range polls {
+		if poll.Status != domain.StatusScheduled || poll.OpensAt > now.Unix() {
+			break
+		}
+		if err := r.setStatus(poll, domain.StatusActive, ""); err != nil {
+			return opened, err
+		}
+		poll.Status = domain.StatusActive
+		opened = append(opened, poll)
+	}
+	return opened, nil
+}
+
+func (r *PollsTarantool) CloseExpiredDB(now time.Time) ([]domain.Poll, error) {
+	polls, err := r.selectPolls("expires", tarantool.IterGe, []interface{}{domain.StatusActive, uint64(1)}, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []domain.Poll
+	for _, poll := range polls {
+		if poll.Status != domain.StatusActive || poll.ExpiresAt > now.Unix() {
+			break
+		}
+		if err := r.setStatus(poll, domain.StatusClosed, ""); err != nil {
+			return expired, err
+		}
+		poll.Status = domain.StatusClosed
+		expired = append(expired, poll)
+	}
+	return expired, nil
+}
+
+// ListDB возвращает страницу голосований канала, новые сначала. Черновики
+// видны только их создателю.
+func (r *PollsTarantool) ListDB(channelID string, userID string, filter string, page int) (domain.PollPage, error) {
+	key := []interface{}{channelID}
+	switch filter {
+	case "", domain.ListActive:
+		filter = domain.ListActive
+		key = append(key, domain.StatusActive)
+	case domain.ListClosed, domain.ListAll:
+	default:
+		return domain.PollPage{}, fmt.Errorf("неизвестный фильтр %s, используйте active, closed или all", filter)
+	}
+	polls, err := r.selectPolls("channel", tarantool.IterEq, key, math.MaxUint32)
+	if err != nil {
+		return domain.PollPage{}, err
+	}
+	polls = slices.DeleteFunc(polls, func(poll domain.Poll) bool {
+		switch {
+		case filter == domain.ListClosed:
+			return poll.Status != domain.StatusClosed && poll.Status != domain.StatusArchived
+		case poll.Status == domain.StatusDraft:
+			return poll.CreatorID != userID
+		}
+		return false
+	})
+	slices.SortFunc(polls, func(a, b domain.Poll) int {
+		return cmp.Compare(createdAt(b), createdAt(a))
+	})
+	return r.pollPage(polls, page)
+}
+
+// MineDB возвращает страницу голосований во всех каналах, которые
+// пользователь создал или в которых проголосовал. Сначала идут голосования с
+// самой свежей активностью: сменой статуса или голосом пользователя.
+func (r *PollsTarantool) MineDB(userID string, page int) (domain.PollPage, error) {
+	polls, err := r.selectPolls("creator", tarantool.IterEq, []interface{}{userID}, math.MaxUint32)
+	if err != nil {
+		return domain.PollPage{}, err
+	}
+	resp, err := r.db.Do(
+		tarantool.NewSelectRequest("ballots").
+			Index("user").
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{userID}),
+	).Get()
+	if err != nil {
+		return domain.PollPage{}, err
+	}
+	votedAt := make(map[string]int64, len(resp))
+	for _, rawRow := range resp {
+		row, ok := rawRow.([]interface{})
+		if !ok || len(row) <= ballotFieldCastAt {
+			return domain.PollPage{}, fmt.Errorf("неожиданный формат данных: %v", rawRow)
+		}
+		pollID, _ := row[ballotFieldPollID].(string)
+		castAt, _ := toInt(row[ballotFieldCastAt])
+		votedAt[pollID] = int64(castAt)
+	}
+	for pollID := range votedAt {
+		if slices.ContainsFunc(polls, func(poll domain.Poll) bool { return poll.ID == pollID }) {
+			continue
+		}
+		poll, err := r.getPollByID(pollID)
+		if err != nil {
+			return domain.PollPage{}, err
+		}
+		polls = append(polls, poll)
+	}
+
+	activeAt := func(poll domain.Poll) int64 {
+		at := votedAt[poll.ID]
+		if len(poll.History) > 0 {
+			at = max(at, poll.History[len(poll.History)-1].At)
+		}
+		return at
+	}
+	slices.SortFunc(polls, func(a, b domain.Poll) int {
+		return cmp.Compare(activeAt(b), activeAt(a))
+	})
+	result, err := r.pollPage(polls, page)
+	if err != nil {
+		return domain.PollPage{}, err
+	}
+	for i, summary := range result.Polls {
+		poll := polls[(page-1)*domain.PageSize+i]
+		summary.Created = poll.CreatorID == userID
+		_, summary.Voted = votedAt[poll.ID]
+		summary.ActiveAt = activeAt(poll)
+		result.Polls[i] = summary
+	}
+	return result, nil
+}
+
+// pollPage собирает страницу списка голосований вместе с числом
+// проголосовавших в каждом из них.
+func (r *PollsTarantool) pollPage(polls []domain.Poll, page int) (domain.PollPage, error) {
+	if page < 1 {
+		return domain.PollPage{}, fmt.Errorf("номер страницы должен быть положительным")
+	}
+	result := domain.PollPage{Page: page, Pages: max(1, (len(polls)+domain.PageSize-1)/domain.PageSize)}
+	start := min((page-1)*domain.PageSize, len(polls))
+	for _, poll := range polls[start:min(start+domain.PageSize, len(polls))] {
+		ballots, err := r.getBallots(poll)
+		if err != nil {
+			return domain.PollPage{}, err
+		}
+		result.Polls = append(result.Polls, domain.PollSummary{
+			ID:       poll.ID,
+			ShortID:  poll.ShortID,
+			Question: poll.Question,
+			Status:   poll.Status,
+			Voters:   len(ballots),
+		})
+	}
+	return result, nil
+}
+
+func createdAt(poll domain.Poll) int64 {
+	if len(poll.History) == 0 {
+		return 0
+	}
+	return poll.History[0].At
+}
+
+// DueRemindersDB возвращает активные голосования, которым пора разослать
+// автоматическое напоминание. Индекс remind упорядочивает их по времени
+// напоминания, а отправленные напоминания из него выпадают, поэтому
+// голосования сверх одной выборки обрабатываются на следующих проверках.
+func (r *PollsTarantool) DueRemindersDB(now time.Time) ([]domain.Poll, error) {
+	polls, err := r.selectPolls("remind", tarantool.IterGe, []interface{}{domain.StatusActive, uint64(1)}, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []domain.Poll
+	for _, poll := range polls {
+		if poll.Status != domain.StatusActive || remindAt(poll) > now.Unix() {
+			break
+		}
+		due = append(due, poll)
+	}
+	return due, nil
+}
+
+func (r *PollsTarantool) MarkRemindedDB(pollID string) error {
+	return r.updatePoll(pollID, tarantool.NewOperations().
+		Assign(pollFieldReminded, true).
+		Assign(pollFieldRemindAt, uint64(0)))
+}
+
+func (r *PollsTarantool) setStatus(poll domain.Poll, status string, userID string) error {
+	ops, err := statusOperations(poll, status, userID)
+	if err != nil {
+		return err
+	}
+	return r.updateStatus(poll.ID, status, ops)
+}
+
+func (r *PollsTarantool) updateStatus(pollID string, status string, ops *tarantool.Operations) error {
+	data, err := r.db.Do(
+		tarantool.NewUpdateRequest("polls").
+			Key([]interface{}{pollID}).
+			Operations(ops),
+	).Get()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Any("data", data).Msgf("Голосование переведено в статус %s", status)
+	return nil
+}
+
+// statusOperations проверяет допустимость перехода и готовит операции,
+// которые меняют статус и дописывают переход в историю голосования.
+func statusOperations(poll domain.Poll, status string, userID string) (*tarantool.Operations, error) {
+	if !domain.CanTransition(poll.Status, status) {
+		return nil, fmt.Errorf("нельзя перевести голосование %s из статуса «%s» в статус «%s»",
+			poll.ID, domain.StatusTitle(poll.Status), domain.StatusTitle(status))
+	}
+	history := append(slices.Clone(poll.History), domain.StatusChange{
+		Status: status,
+		At:     time.Now().Unix(),
+		UserID: userID,
+	})
+	return tarantool.NewOperations().
+		Assign(pollFieldStatus, status).
+		Assign(pollFieldHistory, historyTuple(history)), nil
+}
+
+func (r *PollsTarantool) selectPolls(index string, iterator tarantool.Iter, key []interface{}, limit uint32) ([]domain.Poll, error) {
+	resp, err := r.db.Do(
+		tarantool.NewSelectRequest("polls").
+			Index(index).
+			Limit(limit).
+			Iterator(iterator).
+			Key(key),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	polls := make([]domain.Poll, 0, len(resp))
+	for _, rawRow := range resp {
+		row, ok := rawRow.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("неожиданный формат данных: %v", rawRow)
+		}
+		poll, err := parsePoll(row)
+		if err != nil {
+			return nil, err
+		}
+		polls = append(polls, poll)
+	}
+	return polls, nil
+}
 func (r *PollsTarantool) DeleteDB(pollID string, creatorId string) error {
-	pollData, err := r.getPollByID(pollID)
+	poll, err := r.getPollByID(pollID)
 	if err != nil {
 		return err
 	}
 
-	creatorID, ok := pollData[3].(string)
-	if !ok || creatorID != creatorId {
-		return fmt.Errorf("только создатель может удалить голосование")
+	if poll.CreatorID != creatorId {
+		return fmt.Errorf("%w: только создатель может удалить голосование", domain.ErrForbidden)
 	}
 
 	data, err := r.db.Do(
 		tarantool.NewDeleteRequest("polls").
-			Key([]interface{}{pollID}),
+			Key([]interface{}{poll.ID}),
 	).Get()
 	if err != nil {
 		return err
 	}
+	if err := r.deleteBallots(poll.ID); err != nil {
+		return err
+	}
+	if err := r.unindexPoll(poll.ID); err != nil {
+		return err
+	}
 
 	logger.Log.Debug().Any("data", data).Msg("Голосование удалено")
 	return nil
 }
 
-func (r *PollsTarantool) getPollByID(pollID string) ([]interface{}, error) {
+// getPollByID находит голосование по полному или короткому ID.
+func (r *PollsTarantool) getPollByID(pollID string) (domain.Poll, error) {
+	index, key := "primary", pollID
+	if domain.IsShortID(pollID) {
+		index, key = "short_id", strings.ToUpper(pollID)
+	}
 	resp, err := r.db.Do(
 		tarantool.NewSelectRequest("polls").
+			Index(index).
 			Limit(1).
 			Iterator(tarantool.IterEq).
-			Key([]interface{}{pollID}),
+			Key([]interface{}{key}),
 	).Get()
 	if err != nil {
-		return nil, err
+		return domain.Poll{}, err
 	}
 	if len(resp) == 0 {
-		return nil, fmt.Errorf("голосование %s не найдено", pollID)
+		return domain.Poll{}, fmt.Errorf("голосование %s не найдено", pollID)
 	}
 
 	pollData, ok := resp[0].([]interface{})
 	if !ok {
-		return nil, fmt.Errorf("некорректный формат данных")
+		return domain.Poll{}, fmt.Errorf("некорректный формат данных")
+	}
+
+	return parsePoll(pollData)
+}
+
+// getBallots возвращает бюллетени голосования. Бюллетени, в которых не
+// осталось вариантов после их удаления из голосования, не учитываются.
+func (r *PollsTarantool) getBallots(poll domain.Poll) ([]domain.Ballot, error) {
+	rows, err := r.selectBallots(poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	ballots := make([]domain.Ballot, 0, len(rows))
+	for _, row := range rows {
+		ballot, err := parseBallot(poll, row)
+		if err != nil {
+			return nil, err
+		}
+		if len(ballot.Options) > 0 {
+			ballots = append(ballots, ballot)
+		}
+	}
+	return ballots, nil
+}
+
+func (r *PollsTarantool) selectBallots(pollID string) ([][]interface{}, error) {
+	resp, err := r.db.Do(
+		tarantool.NewSelectRequest("ballots").
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{pollID}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]interface{}, 0, len(resp))
+	for _, rawRow := range resp {
+		row, ok := rawRow.([]interface{})
+		if !ok || len(row) <= ballotFieldUserID {
+			return nil, fmt.Errorf("неожиданный формат данных: %v", rawRow)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (r *PollsTarantool) hasBallot(pollID string, userID string) (bool, error) {
+	resp, err := r.db.Do(
+		tarantool.NewSelectRequest("ballots").
+			Limit(1).
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{pollID, userID}),
+	).Get()
+	if err != nil {
+		return false, err
+	}
+	return len(resp) > 0, nil
+}
+
+func (r *PollsTarantool) deleteBallots(pollID string) error {
+	rows, err := r.selectBallots(pollID)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		_, err := r.db.Do(
+			tarantool.NewDeleteRequest("ballots").
+				Key([]interface{}{row[ballotFieldPollID], row[ballotFieldUserID]}),
+		).Get()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func initialStatus(poll domain.Poll, now int64) string {
+	if poll.OpensAt > now {
+		return domain.StatusScheduled
+	}
+	return domain.StatusActive
+}
+
+func checkActive(poll domain.Poll) error {
+	switch poll.Status {
+	case domain.StatusActive:
+		return nil
+	case domain.StatusClosed, domain.StatusArchived:
+		return fmt.Errorf("голосование с ID %s уже закрыто", poll.ID)
+	default:
+		return fmt.Errorf("голосование с ID %s еще не открыто", poll.ID)
 	}
+}
 
-	return pollData, nil
+// checkEligible проверяет, что пользователь входит в список участников
+// голосования, если такой список задан.
+func checkEligible(poll domain.Poll, userID string) error {
+	if len(poll.Eligible) > 0 && !slices.Contains(poll.Eligible, userID) {
+		return fmt.Errorf("%w: %s", domain.ErrIneligible, poll.ID)
+	}
+	return nil
+}
+
+func checkBallot(poll domain.Poll, ballot domain.Ballot) error {
+	if err := checkActive(poll); err != nil {
+		return err
+	}
+	if len(ballot.Options) == 0 {
+		return fmt.Errorf("нужно выбрать хотя бы один вариант ответа")
+	}
+	if len(ballot.Options) > poll.MaxChoices {
+		return fmt.Errorf("в голосовании %s можно выбрать не больше %d вариантов", poll.ID, poll.MaxChoices)
+	}
+	chosen := make(map[string]bool, len(ballot.Options))
+	for _, option := range ballot.Options {
+		if chosen[option] {
+			return fmt.Errorf("вариант ответа %s выбран несколько раз", option)
+		}
+		chosen[option] = true
+		if !slices.Contains(poll.Options, option) {
+			if suggestion, ok := suggestOption(poll.Options, option); ok {
+				return fmt.Errorf("вариант ответа %s не найден в голосовании, возможно, вы имели в виду «%s»", option, suggestion)
+			}
+			return fmt.Errorf("вариант ответа %s не найден в голосовании", option)
+		}
+	}
+
+	switch poll.Kind {
+	case domain.KindScore:
+		if len(ballot.Values) != len(ballot.Options) {
+			return fmt.Errorf("укажите оценку для каждого варианта в виде \"вариант\"=оценка")
+		}
+		for i, value := range ballot.Values {
+			if value < domain.MinScore || value > domain.MaxScore {
+				return fmt.Errorf("оценка варианта %s должна быть от %d до %d", ballot.Options[i], domain.MinScore, domain.MaxScore)
+			}
+		}
+	case domain.KindSchedule:
+		if len(ballot.Values) != len(ballot.Options) {
+			return fmt.Errorf("отметьте каждый слот в виде \"слот\"=yes, maybe или no")
+		}
+		for i, value := range ballot.Values {
+			if value < domain.AvailabilityNo || value > domain.AvailabilityYes {
+				return fmt.Errorf("отметка слота %s должна быть yes, maybe или no", ballot.Options[i])
+			}
+		}
+	default:
+		if len(ballot.Values) > 0 {
+			return fmt.Errorf("оценки принимаются только в оценочном голосовании")
+		}
+	}
+	return nil
 }
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func rankedBallots(groups ...[]string) []domain.Ballot {
+	var ballots []domain.Ballot
+	for _, group := range groups {
+		ballots = append(ballots, domain.Ballot{Options: group})
+	}
+	return ballots
+}
+
+func repeat(n int, options ...string) [][]string {
+	groups := make([][]string, n)
+	for i := range groups {
+		groups[i] = options
+	}
+	return groups
+}
+
+func TestInstantRunoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    []string
+		ballots    [][]string
+		winner     string
+		eliminated [][]string
+	}{
+		{
+			name:       "majority in first round",
+			options:    []string{"A", "B", "C"},
+			ballots:    slices.Concat(repeat(3, "A"), repeat(1, "B"), repeat(1, "C")),
+			winner:     "A",
+			eliminated: nil,
+		},
+		{
+			name:       "tied last place eliminated one at a time",
+			options:    []string{"A", "B", "C"},
+			ballots:    slices.Concat(repeat(3, "A"), repeat(2, "B", "C"), repeat(2, "C", "B")),
+			winner:     "B",
+			eliminated: [][]string{{"C"}},
+		},
+		{
+			name:       "tied options below next place eliminated together",
+			options:    []string{"A", "B", "C", "D"},
+			ballots:    slices.Concat(repeat(4, "A"), repeat(3, "B"), repeat(1, "C", "B"), repeat(1, "D", "B")),
+			winner:     "B",
+			eliminated: [][]string{{"C", "D"}},
+		},
+		{
+			name:       "earlier round breaks tie for last place",
+			options:    []string{"A", "B", "C", "D"},
+			ballots:    slices.Concat(repeat(4, "A"), repeat(2, "B"), repeat(3, "C"), repeat(1, "D", "B")),
+			winner:     "A",
+			eliminated: [][]string{{"D"}, {"B"}},
+		},
+		{
+			name:       "all remaining tied",
+			options:    []string{"A", "B"},
+			ballots:    slices.Concat(repeat(2, "A"), repeat(2, "B")),
+			winner:     "",
+			eliminated: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll := domain.Poll{Kind: domain.KindRanked, Options: tt.options}
+			rounds, winner := instantRunoff(poll, rankedBallots(tt.ballots...))
+			if winner != tt.winner {
+				t.Errorf("winner = %q, want %q", winner, tt.winner)
+			}
+			var eliminated [][]string
+			for _, round := range rounds {
+				if len(round.Eliminated) > 0 {
+					eliminated = append(eliminated, round.Eliminated)
+				}
+			}
+			if !slices.EqualFunc(eliminated, tt.eliminated, slices.Equal) {
+				t.Errorf("eliminated = %v, want %v", eliminated, tt.eliminated)
+			}
+		})
+	}
+}
+
+func TestScoreResults(t *testing.T) {
+	poll := domain.Poll{Kind: domain.KindScore, Options: []string{"A", "B", "C"}}
+	ballots := []domain.Ballot{
+		{Options: []string{"A", "B"}, Values: []int{5, 1}},
+		{Options: []string{"A", "B"}, Values: []int{3, 2}, Weight: 2},
+		{Options: []string{"A"}, Values: []int{4}},
+	}
+	want := []domain.ScoreResult{
+		{Option: "A", Count: 3, Mean: 3.75, Median: 3.5, Histogram: []int{0, 0, 2, 1, 1}},
+		{Option: "B", Count: 2, Mean: 5.0 / 3, Median: 2, Histogram: []int{1, 2, 0, 0, 0}},
+		{Option: "C", Count: 0, Histogram: []int{0, 0, 0, 0, 0}},
+	}
+	got := scoreResults(poll, ballots)
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Option != want[i].Option || got[i].Count != want[i].Count ||
+			got[i].Mean != want[i].Mean || got[i].Median != want[i].Median ||
+			!slices.Equal(got[i].Histogram, want[i].Histogram) {
+			t.Errorf("result %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSchulze(t *testing.T) {
+	poll := domain.Poll{Kind: domain.KindSchulze, Options: []string{"A", "B", "C", "D", "E"}}
+	ballots := rankedBallots(slices.Concat(
+		repeat(5, "A", "C", "B", "E", "D"),
+		repeat(5, "A", "D", "E", "C", "B"),
+		repeat(8, "B", "E", "D", "A", "C"),
+		repeat(3, "C", "A", "B", "E", "D"),
+		repeat(7, "C", "A", "E", "B", "D"),
+		repeat(2, "C", "B", "A", "D", "E"),
+		repeat(7, "D", "C", "E", "B", "A"),
+		repeat(8, "E", "B", "A", "D", "C"),
+	)...)
+	pairwise, paths := schulze(poll, ballots)
+	if pairwise[0][1] != 20 || pairwise[1][0] != 25 {
+		t.Errorf("pairwise A/B = %d/%d, want 20/25", pairwise[0][1], pairwise[1][0])
+	}
+	wantPaths := [][]int{
+		{0, 28, 28, 30, 24},
+		{25, 0, 28, 33, 24},
+		{25, 29, 0, 29, 24},
+		{25, 28, 28, 0, 24},
+		{25, 28, 28, 31, 0},
+	}
+	if !slices.EqualFunc(paths, wantPaths, slices.Equal) {
+		t.Errorf("paths = %v, want %v", paths, wantPaths)
+	}
+	results := domain.PollResults{Voters: len(ballots), Paths: paths}
+	if best := leaders(poll, results); !slices.Equal(best, []string{"E"}) {
+		t.Errorf("leaders = %v, want [E]", best)
+	}
+}
+
+func TestScheduleResults(t *testing.T) {
+	poll := domain.Poll{Options: []string{"10:00", "11:00", "12:00"}}
+	ballots := []domain.Ballot{
+		{Options: []string{"10:00", "11:00"}, Values: []int{domain.AvailabilityYes, domain.AvailabilityMaybe}},
+		{Options: []string{"11:00", "12:00"}, Values: []int{domain.AvailabilityYes, domain.AvailabilityNo}, Weight: 2},
+		{},
+	}
+	want := []domain.SlotResult{
+		{Option: "10:00", Yes: 1, No: 3},
+		{Option: "11:00", Yes: 2, Maybe: 1, No: 1},
+		{Option: "12:00", No: 4},
+	}
+	if got := scheduleResults(poll, ballots); !slices.Equal(got, want) {
+		t.Errorf("scheduleResults() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRevoteOperations(t *testing.T) {
+	poll := domain.Poll{Kind: domain.KindPlurality, Options: []string{"A", "B"}, OptionIDs: []int{1, 2}}
+	ops := revoteOperations(poll, domain.Ballot{Options: []string{"B"}, CastAt: 100})
+
+	encoded, err := msgpack.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded [][]interface{}
+	if err := msgpack.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 {
+		t.Fatalf("got %d operations, want 3: %v", len(decoded), decoded)
+	}
+	if options, _ := toInts(decoded[0][2]); !slices.Equal(options, []int{2}) {
+		t.Errorf("options = %v, want [2]", decoded[0][2])
+	}
+	values, ok := decoded[2][2].([]interface{})
+	if !ok || len(values) != 0 {
+		t.Errorf("values = %#v, want an empty array", decoded[2][2])
+	}
+}
+
+func TestRemindAt(t *testing.T) {
+	tests := []struct {
+		name string
+		poll domain.Poll
+		want int64
+	}{
+		{"before deadline", domain.Poll{ExpiresAt: 10000, RemindBefore: 3600}, 6400},
+		{"no reminder", domain.Poll{ExpiresAt: 10000}, 0},
+		{"no deadline", domain.Poll{RemindBefore: 3600}, 0},
+		{"already reminded", domain.Poll{ExpiresAt: 10000, RemindBefore: 3600, Reminded: true}, 0},
+		{"reminder longer than poll", domain.Poll{ExpiresAt: 100, RemindBefore: 3600}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := remindAt(tt.poll); got != tt.want {
+				t.Errorf("remindAt() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	logger "github.com/bllooop/votingbot/pkg/logging"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+type WeightsTarantool struct {
+	db *tarantool.Connection
+}
+
+func NewWeightsTarantool(db *tarantool.Connection) *WeightsTarantool {
+	return &WeightsTarantool{
+		db: db,
+	}
+}
+
+func (r *WeightsTarantool) SetWeightDB(weight domain.Weight) error {
+	data, err := r.db.Do(
+		tarantool.NewReplaceRequest("weights").
+			Tuple([]interface{}{weight.Scope, weight.UserID, uint64(weight.Weight)}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	logger.Log.Debug().Any("data", data).Msg("Установлен вес голоса")
+	return nil
+}
+
+func (r *WeightsTarantool) RemoveWeightDB(scope string, userID string) error {
+	data, err := r.db.Do(
+		tarantool.NewDeleteRequest("weights").
+			Key([]interface{}{scope, userID}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("вес голоса пользователя %s не задан", userID)
+	}
+	logger.Log.Debug().Any("data", data).Msg("Удален вес голоса")
+	return nil
+}
+
+func (r *WeightsTarantool) ListWeightsDB(scope string) ([]domain.Weight, error) {
+	return selectWeights(r.db, scope)
+}
+
+// voterWeights возвращает веса участников голосования: вес, заданный для
+// голосования, важнее веса, заданного для канала.
+func voterWeights(db *tarantool.Connection, poll domain.Poll) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, scope := range []string{domain.ChannelScope(poll.ChannelID), domain.PollScope(poll.ID)} {
+		scoped, err := selectWeights(db, scope)
+		if err != nil {
+			return nil, err
+		}
+		for _, weight := range scoped {
+			weights[weight.UserID] = weight.Weight
+		}
+	}
+	return weights, nil
+}
+
+func selectWeights(db *tarantool.Connection, scope string) ([]domain.Weight, error) {
+	resp, err := db.Do(
+		tarantool.NewSelectRequest("weights").
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{scope}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make([]domain.Weight, 0, len(resp))
+	for _, rawRow := range resp {
+		row, ok := rawRow.([]interface{})
+		if !ok || len(row) < 3 {
+			return nil, fmt.Errorf("неожиданный формат данных: %v", rawRow)
+		}
+		weight := domain.Weight{}
+		weight.Scope, _ = row[0].(string)
+		weight.UserID, _ = row[1].(string)
+		weight.Weight, _ = toInt(row[2])
+		weights = append(weights, weight)
+	}
+	return weights, nil
+}
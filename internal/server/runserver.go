@@ -5,10 +5,12 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	handlers "github.com/bllooop/votingbot/internal/delivery/api"
+	"github.com/bllooop/votingbot/internal/mattermost"
 	"github.com/bllooop/votingbot/internal/repository"
 	"github.com/bllooop/votingbot/internal/usecase"
 	logger "github.com/bllooop/votingbot/pkg/logging"
@@ -42,11 +44,21 @@ func Run() {
 	logger.Log.Debug().Msg("Инициализация слоя репозитория")
 	repos := repository.NewRepository(dbpool)
 	logger.Log.Debug().Msg("Инициализация usecase слоя")
-	usecases := usecase.NewUsecase(repos)
+	mmClient := mattermost.NewClient(viper.GetString("mattermost.url"), os.Getenv("MATTERMOST_TOKEN"))
+	usecases := usecase.NewUsecase(repos, viper.GetStringSlice("admins"), mmClient)
 	logger.Log.Debug().Msg("Инициализация обработчиков API")
 	handler := handlers.NewHandler(usecases)
 	srv := new(Server)
 
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		logger.Log.Info().Msg("Запуск обработчика голосований")
+		NewPollWorker(usecases, mmClient, viper.GetDuration("worker.interval")).Run(workerCtx)
+	}()
+
 	go func() {
 		logger.Log.Info().Msg("Запуск сервера...")
 		if err := srv.RunServer(viper.GetString("port"), handler.InitRoutes()); err != nil && err == http.ErrServerClosed {
@@ -62,6 +74,8 @@ func Run() {
 	logger.Log.Debug().Msg("Прослушивание сигналов завершения работы ОС")
 	<-quit
 	logger.Log.Info().Msg("Сервер отключается")
+	stopWorker()
+	workers.Wait()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	defer dbpool.Close()
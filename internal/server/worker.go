@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	handlers "github.com/bllooop/votingbot/internal/delivery/api"
+	"github.com/bllooop/votingbot/internal/mattermost"
+	"github.com/bllooop/votingbot/internal/usecase"
+	logger "github.com/bllooop/votingbot/pkg/logging"
+)
+
+// PollWorker периодически открывает запланированные голосования, закрывает
+// голосования с истекшим сроком и сообщает об этом в канал, где голосование
+// было создано, а также рассылает автоматические напоминания.
+type PollWorker struct {
+	usecases *usecase.Usecase
+	client   mattermost.API
+	interval time.Duration
+}
+
+func NewPollWorker(usecases *usecase.Usecase, client mattermost.API, interval time.Duration) *PollWorker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &PollWorker{
+		usecases: usecases,
+		client:   client,
+		interval: interval,
+	}
+}
+
+func (w *PollWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Info().Msg("Обработчик голосований остановлен")
+			return
+		case now := <-ticker.C:
+			w.openScheduled(now)
+			w.closeExpired(now)
+			w.remindDue(now)
+		}
+	}
+}
+
+func (w *PollWorker) openScheduled(now time.Time) {
+	polls, err := w.usecases.Polls.OpenScheduledDB(now)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Не удалось открыть запланированные голосования")
+	}
+	for _, poll := range polls {
+		logger.Log.Info().Msgf("Голосование %s открыто по расписанию", poll.ID)
+		if poll.ChannelID == "" {
+			continue
+		}
+		message := fmt.Sprintf("Голосование открыто! ID: %s, %s, Варианты ответов: %s", poll.ShortID, poll.Question, handlers.FormatOptions(poll.Options))
+		if err := w.client.CreatePost(poll.ChannelID, message); err != nil {
+			logger.Log.Error().Err(err).Msgf("Не удалось сообщить об открытии голосования %s", poll.ID)
+		}
+	}
+}
+
+func (w *PollWorker) closeExpired(now time.Time) {
+	polls, err := w.usecases.Polls.CloseExpiredDB(now)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Не удалось закрыть истекшие голосования")
+	}
+	for _, poll := range polls {
+		logger.Log.Info().Msgf("Голосование %s закрыто по истечении срока", poll.ID)
+		results, err := w.usecases.Polls.GetRes(poll.ID, "", false)
+		if err != nil {
+			logger.Log.Error().Err(err).Msgf("Не удалось получить итоги голосования %s", poll.ID)
+			continue
+		}
+		if poll.ChannelID == "" {
+			continue
+		}
+		if err := w.client.CreatePost(poll.ChannelID, handlers.FormatResults(results)); err != nil {
+			logger.Log.Error().Err(err).Msgf("Не удалось опубликовать итоги голосования %s", poll.ID)
+		}
+	}
+}
+
+func (w *PollWorker) remindDue(now time.Time) {
+	polls, err := w.usecases.Reminders.RemindDueDB(now)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Не удалось разослать автоматические напоминания")
+	}
+	for _, poll := range polls {
+		logger.Log.Info().Msgf("Разосланы напоминания о голосовании %s", poll.ID)
+	}
+}
@@ -1,31 +1,240 @@
 package usecase
 
 import (
+	"fmt"
+	"slices"
+	"time"
+
 	"github.com/bllooop/votingbot/internal/domain"
+	"github.com/bllooop/votingbot/internal/mattermost"
 	"github.com/bllooop/votingbot/internal/repository"
+	logger "github.com/bllooop/votingbot/pkg/logging"
 )
 
 type PollsUsecase struct {
-	repo repository.Polls
+	repo   repository.Polls
+	client mattermost.API
 }
 
-func NewPollsUsecase(repo *repository.Repository) *PollsUsecase {
+func NewPollsUsecase(repo *repository.Repository, client mattermost.API) *PollsUsecase {
 	return &PollsUsecase{
-		repo: repo,
+		repo:   repo,
+		client: client,
+	}
+}
+
+// CreateDB создает голосование. Если голосовать разрешено только отдельным
+// пользователям и группам, их участники определяются в момент создания.
+func (s *PollsUsecase) CreateDB(poll domain.Poll) (string, []string, error) {
+	if len(poll.EligibleNames) > 0 {
+		eligible, err := resolveMentions(s.client, poll.EligibleNames)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(eligible) == 0 {
+			return "", nil, fmt.Errorf("в списке допущенных к голосованию нет ни одного пользователя")
+		}
+		poll.Eligible = eligible
 	}
+	return s.repo.CreateDB(poll)
 }
-func (s *PollsUsecase) CreateDB(question string, options []string, creatorId string) (string, []string, error) {
-	return s.repo.CreateDB(question, options, creatorId)
+func (s *PollsUsecase) CastDB(ballot domain.Ballot) ([]string, error) {
+	return s.repo.CastDB(ballot)
 }
-func (s *PollsUsecase) CastDB(pollID string, option string) error {
-	return s.repo.CastDB(pollID, option)
+func (s *PollsUsecase) RevoteDB(ballot domain.Ballot) ([]string, error) {
+	return s.repo.RevoteDB(ballot)
 }
-func (s *PollsUsecase) GetRes(pollID string) (domain.Results, error) {
-	return s.repo.GetRes(pollID)
+func (s *PollsUsecase) RetractDB(pollID string, userID string) error {
+	return s.repo.RetractDB(pollID, userID)
+}
+
+// GetRes возвращает результаты голосования. В слепом голосовании до его
+// закрытия подсчет скрыт и виден только создателю, когда он запрашивает
+// результаты с peek.
+func (s *PollsUsecase) GetRes(pollID string, userID string, peek bool) (domain.PollResults, error) {
+	results, err := s.repo.GetRes(pollID)
+	if err != nil {
+		return domain.PollResults{}, err
+	}
+	if peek && results.CreatorID != userID {
+		return domain.PollResults{}, fmt.Errorf("%w: подсмотреть результаты может только создатель голосования", domain.ErrForbidden)
+	}
+	if !results.Blind || peek || results.Status == domain.StatusClosed || results.Status == domain.StatusArchived {
+		return results, nil
+	}
+	return hideTallies(results), nil
+}
+
+// VotersDB возвращает бюллетени с авторами только для публичных голосований.
+// Для слепого голосования список доступен после закрытия, иначе по нему
+// можно восстановить скрытый подсчет.
+func (s *PollsUsecase) VotersDB(pollID string) (domain.Poll, []domain.Ballot, error) {
+	poll, ballots, err := s.repo.VotersDB(pollID)
+	if err != nil {
+		return domain.Poll{}, nil, err
+	}
+	if !poll.Public {
+		return domain.Poll{}, nil, fmt.Errorf("%w: голосование %s анонимное", domain.ErrForbidden, pollID)
+	}
+	if poll.Blind && poll.Status != domain.StatusClosed && poll.Status != domain.StatusArchived {
+		return domain.Poll{}, nil, fmt.Errorf("%w: участники слепого голосования %s будут видны после его закрытия", domain.ErrForbidden, pollID)
+	}
+	return poll, ballots, nil
 }
 func (s *PollsUsecase) CloseDB(pollID string, creatorId string) error {
-	return s.repo.CloseDB(pollID, creatorId)
+	if err := s.repo.CloseDB(pollID, creatorId); err != nil {
+		return err
+	}
+	if err := s.startRunoff(pollID); err != nil {
+		logger.Log.Error().Err(err).Msgf("Не удалось назначить второй тур голосования %s", pollID)
+	}
+	return nil
+}
+func (s *PollsUsecase) CloseExpiredDB(now time.Time) ([]domain.Poll, error) {
+	polls, err := s.repo.CloseExpiredDB(now)
+	for _, poll := range polls {
+		if err := s.startRunoff(poll.ID); err != nil {
+			logger.Log.Error().Err(err).Msgf("Не удалось назначить второй тур голосования %s", poll.ID)
+		}
+	}
+	return polls, err
+}
+func (s *PollsUsecase) PublishDB(pollID string, creatorId string) (domain.Poll, error) {
+	return s.repo.PublishDB(pollID, creatorId)
+}
+func (s *PollsUsecase) ReopenDB(pollID string, creatorId string, expiresAt int64) (domain.Poll, error) {
+	return s.repo.ReopenDB(pollID, creatorId, expiresAt)
+}
+func (s *PollsUsecase) TieBreakDB(pollID string, creatorId string, option string) error {
+	results, err := s.repo.GetRes(pollID)
+	if err != nil {
+		return err
+	}
+	if results.CreatorID != creatorId {
+		return fmt.Errorf("%w: разрешить ничью может только создатель голосования", domain.ErrForbidden)
+	}
+	if results.TieBreak != domain.TieBreakCreator {
+		return fmt.Errorf("в голосовании %s ничью разрешает не создатель", pollID)
+	}
+	if results.Status != domain.StatusClosed && results.Status != domain.StatusArchived {
+		return fmt.Errorf("ничью можно разрешить только после закрытия голосования")
+	}
+	if !slices.Contains(results.Tied, option) {
+		return fmt.Errorf("вариант %s не входит в число вариантов с равным результатом", option)
+	}
+	return s.repo.SetTieWinnerDB(results.PollID, option)
+}
+func (s *PollsUsecase) ApproveDB(pollID string, creatorId string, option string) error {
+	return s.repo.ApproveDB(pollID, creatorId, option)
+}
+func (s *PollsUsecase) EditDB(pollID string, creatorId string, edit domain.PollEdit) error {
+	return s.repo.EditDB(pollID, creatorId, edit)
+}
+func (s *PollsUsecase) ListDB(channelID string, userID string, filter string, page int) (domain.PollPage, error) {
+	return s.repo.ListDB(channelID, userID, filter, page)
+}
+func (s *PollsUsecase) MineDB(userID string, page int) (domain.PollPage, error) {
+	return s.repo.MineDB(userID, page)
+}
+
+// SearchDB ищет голосования канала или команды. В поиске по команде
+// остаются только каналы, в которых состоит пользователь.
+func (s *PollsUsecase) SearchDB(query string, channelID string, teamID string, userID string, page int) (domain.PollPage, error) {
+	var channels []string
+	if channelID == "" {
+		candidates, err := s.repo.SearchChannelsDB(query, teamID)
+		if err != nil {
+			return domain.PollPage{}, err
+		}
+		for _, candidate := range candidates {
+			member, err := s.client.IsChannelMember(candidate, userID)
+			if err != nil {
+				return domain.PollPage{}, fmt.Errorf("не удалось проверить участие в канале: %w", err)
+			}
+			if member {
+				channels = append(channels, candidate)
+			}
+		}
+	}
+	return s.repo.SearchDB(query, channelID, teamID, channels, userID, page)
+}
+func (s *PollsUsecase) ArchiveDB(pollID string, creatorId string) error {
+	return s.repo.ArchiveDB(pollID, creatorId)
+}
+func (s *PollsUsecase) OpenScheduledDB(now time.Time) ([]domain.Poll, error) {
+	return s.repo.OpenScheduledDB(now)
 }
 func (s *PollsUsecase) DeleteDB(pollID string, creatorId string) error {
 	return s.repo.DeleteDB(pollID, creatorId)
 }
+
+func hideTallies(results domain.PollResults) domain.PollResults {
+	results.Hidden = true
+	results.Options = nil
+	results.Rounds = nil
+	results.Scores = nil
+	results.Slots = nil
+	results.Pairwise = nil
+	results.Paths = nil
+	results.Winner = ""
+	results.Tied = nil
+	results.Decision = nil
+	return results
+}
+
+// startRunoff создает второй тур между вариантами с равным результатом, если
+// голосование закрыто вничью и ничья разрешается вторым туром. Поле второго
+// тура сначала занимается отметкой domain.RunoffPending, поэтому закрытие
+// создателем и фоновым обработчиком одновременно создает только один тур.
+func (s *PollsUsecase) startRunoff(pollID string) error {
+	results, err := s.repo.GetRes(pollID)
+	if err != nil {
+		return err
+	}
+	if results.TieBreak != domain.TieBreakRunoff || results.Winner != "" || len(results.Tied) < 2 || results.RunoffID != "" {
+		return nil
+	}
+	claimed, err := s.repo.SetRunoffDB(results.PollID, "", domain.RunoffPending)
+	if err != nil || !claimed {
+		return err
+	}
+	poll, err := s.repo.GetPollDB(results.PollID)
+	if err != nil {
+		return s.releaseRunoff(results.PollID, err)
+	}
+	runoffID, _, err := s.repo.CreateDB(domain.Poll{
+		Question:      "Второй тур: " + poll.Question,
+		Options:       results.Tied,
+		CreatorID:     poll.CreatorID,
+		ChannelID:     poll.ChannelID,
+		TeamID:        poll.TeamID,
+		Blind:         poll.Blind,
+		Public:        poll.Public,
+		TieBreak:      domain.TieBreakCreator,
+		Invited:       poll.Invited,
+		EligibleNames: poll.EligibleNames,
+		Eligible:      poll.Eligible,
+	})
+	if err != nil {
+		return s.releaseRunoff(results.PollID, err)
+	}
+	saved, err := s.repo.SetRunoffDB(results.PollID, domain.RunoffPending, runoffID)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		logger.Log.Warn().Msgf("Голосование %s открыто заново, пока создавался второй тур %s", pollID, runoffID)
+		return nil
+	}
+	logger.Log.Info().Msgf("Для голосования %s назначен второй тур %s", pollID, runoffID)
+	return nil
+}
+
+// releaseRunoff снимает отметку domain.RunoffPending, если второй тур не
+// удалось создать, чтобы его можно было назначить при следующем закрытии.
+func (s *PollsUsecase) releaseRunoff(pollID string, cause error) error {
+	if _, err := s.repo.SetRunoffDB(pollID, domain.RunoffPending, ""); err != nil {
+		logger.Log.Error().Err(err).Msgf("Не удалось снять отметку второго тура голосования %s", pollID)
+	}
+	return cause
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	"github.com/bllooop/votingbot/internal/repository"
+)
+
+// fakeSearch подменяет поиск в хранилище и запоминает, по каким каналам
+// искали голосования.
+type fakeSearch struct {
+	repository.Polls
+	candidates []string
+	searched   []string
+}
+
+func (f *fakeSearch) SearchChannelsDB(query string, teamID string) ([]string, error) {
+	return f.candidates, nil
+}
+
+func (f *fakeSearch) SearchDB(query string, channelID string, teamID string, channels []string, userID string, page int) (domain.PollPage, error) {
+	f.searched = channels
+	return domain.PollPage{}, nil
+}
+
+func TestSearchDBTeamChannels(t *testing.T) {
+	mm := &fakeMattermost{members: map[string][]string{
+		"town-square": {"u1", "u2"},
+		"private":     {"u2"},
+	}}
+	repo := &fakeSearch{candidates: []string{"town-square", "private", "archived"}}
+	s := &PollsUsecase{repo: repo, client: mm.start(t)}
+
+	if _, err := s.SearchDB("обед", "", "team", "u1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"town-square"}; !slices.Equal(repo.searched, want) {
+		t.Errorf("searched channels %v, want %v", repo.searched, want)
+	}
+}
+
+// fakeRunoff подменяет хранилище для второго тура: SetRunoffDB, как и
+// процедура set_poll_runoff, меняет поле только при ожидаемом значении.
+type fakeRunoff struct {
+	repository.Polls
+	poll domain.Poll
+
+	mu      sync.Mutex
+	created []domain.Poll
+}
+
+func (f *fakeRunoff) GetRes(pollID string) (domain.PollResults, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return domain.PollResults{PollID: f.poll.ID, TieBreak: domain.TieBreakRunoff, Tied: []string{"A", "B"}, RunoffID: f.poll.RunoffID}, nil
+}
+
+func (f *fakeRunoff) GetPollDB(pollID string) (domain.Poll, error) {
+	return f.poll, nil
+}
+
+func (f *fakeRunoff) CreateDB(poll domain.Poll) (string, []string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.created = append(f.created, poll)
+	return fmt.Sprintf("P-%d", len(f.created)), poll.Options, nil
+}
+
+func (f *fakeRunoff) SetRunoffDB(pollID string, expected string, runoffID string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.poll.RunoffID != expected {
+		return false, nil
+	}
+	f.poll.RunoffID = runoffID
+	return true, nil
+}
+
+func TestStartRunoff(t *testing.T) {
+	repo := &fakeRunoff{poll: domain.Poll{
+		ID:            "poll",
+		Question:      "Обед",
+		CreatorID:     "creator",
+		ChannelID:     "town-square",
+		TeamID:        "team",
+		EligibleNames: []string{"leads"},
+		Eligible:      []string{"u1", "u2"},
+	}}
+	s := &PollsUsecase{repo: repo}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.startRunoff("poll"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(repo.created) != 1 {
+		t.Fatalf("created %d runoff polls, want 1", len(repo.created))
+	}
+	if repo.poll.RunoffID != "P-1" {
+		t.Errorf("RunoffID = %q, want P-1", repo.poll.RunoffID)
+	}
+	runoff := repo.created[0]
+	if runoff.TeamID != "team" || !slices.Equal(runoff.EligibleNames, []string{"leads"}) || !slices.Equal(runoff.Eligible, []string{"u1", "u2"}) {
+		t.Errorf("runoff poll = %+v, want team and eligible users copied", runoff)
+	}
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	"github.com/bllooop/votingbot/internal/mattermost"
+	"github.com/bllooop/votingbot/internal/repository"
+	logger "github.com/bllooop/votingbot/pkg/logging"
+)
+
+type RemindersUsecase struct {
+	repo   repository.Polls
+	client mattermost.API
+}
+
+func NewRemindersUsecase(repo *repository.Repository, client mattermost.API) *RemindersUsecase {
+	return &RemindersUsecase{
+		repo:   repo,
+		client: client,
+	}
+}
+
+// RemindDB по запросу создателя напоминает о голосовании тем, кто еще не
+// проголосовал, и возвращает число отправленных напоминаний.
+func (s *RemindersUsecase) RemindDB(pollID string, creatorId string) (int, error) {
+	poll, err := s.repo.GetPollDB(pollID)
+	if err != nil {
+		return 0, err
+	}
+	if poll.CreatorID != creatorId {
+		return 0, fmt.Errorf("%w: напоминать о голосовании может только его создатель", domain.ErrForbidden)
+	}
+	if poll.Status != domain.StatusActive {
+		return 0, fmt.Errorf("напоминать можно только об активном голосовании")
+	}
+	return s.remind(poll)
+}
+
+// RemindDueDB рассылает автоматические напоминания голосованиям, срок
+// окончания которых ближе заданного при создании.
+func (s *RemindersUsecase) RemindDueDB(now time.Time) ([]domain.Poll, error) {
+	polls, err := s.repo.DueRemindersDB(now)
+	if err != nil {
+		return nil, err
+	}
+	for i, poll := range polls {
+		// Отметка ставится до рассылки, чтобы сбой Mattermost не приводил к
+		// повторным напоминаниям на каждом шаге обработчика.
+		if err := s.repo.MarkRemindedDB(poll.ID); err != nil {
+			return polls[:i], err
+		}
+		if _, err := s.remind(poll); err != nil {
+			logger.Log.Error().Err(err).Msgf("Не удалось разослать напоминания о голосовании %s", poll.ID)
+		}
+	}
+	return polls, nil
+}
+
+func (s *RemindersUsecase) remind(poll domain.Poll) (int, error) {
+	electorate, err := s.electorate(poll)
+	if err != nil {
+		return 0, err
+	}
+	_, ballots, err := s.repo.VotersDB(poll.ID)
+	if err != nil {
+		return 0, err
+	}
+	message := fmt.Sprintf("Напоминание: вы еще не проголосовали в голосовании %s «%s». Варианты ответов: %s",
+		poll.ShortID, poll.Question, strings.Join(poll.Options, ", "))
+	if poll.ExpiresAt > 0 {
+		message += fmt.Sprintf(". Голосование завершится %s", time.Unix(poll.ExpiresAt, 0).Format("2006-01-02 15:04 MST"))
+	}
+
+	sent := 0
+	for _, userID := range electorate {
+		if slices.ContainsFunc(ballots, func(ballot domain.Ballot) bool { return ballot.UserID == userID }) {
+			continue
+		}
+		if err := s.client.SendDirect(userID, message); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	logger.Log.Info().Msgf("Разослано напоминаний о голосовании %s: %d", poll.ID, sent)
+	return sent, nil
+}
+
+// electorate возвращает ID пользователей, которым адресовано голосование:
+// допущенных к голосованию, приглашенных при создании или, если ни тех, ни
+// других нет, участников канала.
+func (s *RemindersUsecase) electorate(poll domain.Poll) ([]string, error) {
+	if len(poll.Eligible) > 0 {
+		return poll.Eligible, nil
+	}
+	if len(poll.Invited) > 0 {
+		return resolveMentions(s.client, poll.Invited)
+	}
+	if poll.ChannelID == "" {
+		return nil, fmt.Errorf("для голосования %s не указаны ни участники, ни канал", poll.ID)
+	}
+	return s.client.ChannelMembers(poll.ChannelID)
+}
+
+// resolveMentions превращает имена пользователей и групп Mattermost без @ в
+// ID пользователей. Имя, не найденное среди пользователей, ищется среди групп.
+func resolveMentions(client mattermost.API, names []string) ([]string, error) {
+	users, err := client.UserIDs(names)
+	if err != nil {
+		return nil, err
+	}
+	var userIDs []string
+	for _, name := range names {
+		if userID, ok := users[name]; ok {
+			userIDs = append(userIDs, userID)
+			continue
+		}
+		members, err := client.GroupMembers(name)
+		if err != nil {
+			return nil, fmt.Errorf("пользователь или группа %s не найдены: %w", name, err)
+		}
+		userIDs = append(userIDs, members...)
+	}
+	slices.Sort(userIDs)
+	return slices.Compact(userIDs), nil
+}
@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	"github.com/bllooop/votingbot/internal/mattermost"
+	"github.com/bllooop/votingbot/internal/repository"
+)
+
+const botID = "bot"
+
+// fakeMattermost — локальный сервер с теми вызовами API Mattermost, которые
+// нужны напоминаниям и поиску. Он запоминает, кому отправлены личные сообщения.
+type fakeMattermost struct {
+	members map[string][]string
+	users   map[string]string
+	groups  map[string][]string
+
+	mu       sync.Mutex
+	directed []string
+}
+
+func (f *fakeMattermost) start(t *testing.T) mattermost.API {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/users/me", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"id": botID})
+	})
+	mux.HandleFunc("POST /api/v4/channels/direct", func(w http.ResponseWriter, r *http.Request) {
+		var ids []string
+		json.NewDecoder(r.Body).Decode(&ids)
+		json.NewEncoder(w).Encode(map[string]string{"id": "dm-" + ids[1]})
+	})
+	mux.HandleFunc("POST /api/v4/posts", func(w http.ResponseWriter, r *http.Request) {
+		var post struct {
+			ChannelID string `json:"channel_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&post)
+		if userID, ok := strings.CutPrefix(post.ChannelID, "dm-"); ok {
+			f.mu.Lock()
+			f.directed = append(f.directed, userID)
+			f.mu.Unlock()
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("GET /api/v4/channels/{id}/members", func(w http.ResponseWriter, r *http.Request) {
+		var members []map[string]string
+		if r.URL.Query().Get("page") == "0" {
+			for _, userID := range f.members[r.PathValue("id")] {
+				members = append(members, map[string]string{"user_id": userID})
+			}
+		}
+		json.NewEncoder(w).Encode(members)
+	})
+	mux.HandleFunc("GET /api/v4/channels/{id}/members/{user}", func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(f.members[r.PathValue("id")], r.PathValue("user")) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"user_id": r.PathValue("user")})
+	})
+	mux.HandleFunc("POST /api/v4/users/usernames", func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+		json.NewDecoder(r.Body).Decode(&names)
+		users := []map[string]string{}
+		for _, name := range names {
+			if userID, ok := f.users[name]; ok {
+				users = append(users, map[string]string{"id": userID, "username": name})
+			}
+		}
+		json.NewEncoder(w).Encode(users)
+	})
+	mux.HandleFunc("GET /api/v4/groups", func(w http.ResponseWriter, r *http.Request) {
+		groups := []map[string]string{}
+		if name := r.URL.Query().Get("q"); f.groups[name] != nil {
+			groups = append(groups, map[string]string{"id": "group-" + name, "name": name})
+		}
+		json.NewEncoder(w).Encode(groups)
+	})
+	mux.HandleFunc("GET /api/v4/groups/{id}/members", func(w http.ResponseWriter, r *http.Request) {
+		var members []map[string]string
+		if r.URL.Query().Get("page") == "0" {
+			for _, userID := range f.groups[strings.TrimPrefix(r.PathValue("id"), "group-")] {
+				members = append(members, map[string]string{"id": userID})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"members": members})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return mattermost.NewClient(server.URL, "token")
+}
+
+func (f *fakeMattermost) recipients() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	recipients := slices.Clone(f.directed)
+	slices.Sort(recipients)
+	return recipients
+}
+
+// fakePolls подменяет хранилище голосований; вызовы, не нужные
+// напоминаниям, не реализованы.
+type fakePolls struct {
+	repository.Polls
+	polls    map[string]domain.Poll
+	ballots  map[string][]domain.Ballot
+	due      []domain.Poll
+	reminded []string
+}
+
+func (f *fakePolls) GetPollDB(pollID string) (domain.Poll, error) {
+	return f.polls[pollID], nil
+}
+
+func (f *fakePolls) VotersDB(pollID string) (domain.Poll, []domain.Ballot, error) {
+	return f.polls[pollID], f.ballots[pollID], nil
+}
+
+func (f *fakePolls) DueRemindersDB(now time.Time) ([]domain.Poll, error) {
+	return f.due, nil
+}
+
+func (f *fakePolls) MarkRemindedDB(pollID string) error {
+	f.reminded = append(f.reminded, pollID)
+	return nil
+}
+
+func TestRemindDB(t *testing.T) {
+	tests := []struct {
+		name    string
+		poll    domain.Poll
+		ballots []domain.Ballot
+		want    []string
+	}{
+		{
+			name:    "channel members who have not voted",
+			poll:    domain.Poll{ChannelID: "town-square"},
+			ballots: []domain.Ballot{{UserID: "u2"}},
+			want:    []string{"u1", "u3"},
+		},
+		{
+			name:    "eligible users before invited",
+			poll:    domain.Poll{ChannelID: "town-square", Invited: []string{"alice"}, Eligible: []string{"u4", "u5"}},
+			ballots: []domain.Ballot{{UserID: "u5"}},
+			want:    []string{"u4"},
+		},
+		{
+			name:    "invited users and groups before channel members",
+			poll:    domain.Poll{ChannelID: "town-square", Invited: []string{"alice", "leads"}},
+			ballots: []domain.Ballot{{UserID: "u7"}},
+			want:    []string{"u6", "u8"},
+		},
+		{
+			name:    "everyone has voted",
+			poll:    domain.Poll{ChannelID: "town-square"},
+			ballots: []domain.Ballot{{UserID: "u1"}, {UserID: "u2"}, {UserID: "u3"}},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mm := &fakeMattermost{
+				members: map[string][]string{"town-square": {botID, "u1", "u2", "u3"}},
+				users:   map[string]string{"alice": "u6"},
+				groups:  map[string][]string{"leads": {"u6", "u7", "u8"}},
+			}
+			poll := tt.poll
+			poll.ID, poll.CreatorID, poll.Status = "poll", "creator", domain.StatusActive
+			repo := &fakePolls{
+				polls:   map[string]domain.Poll{poll.ID: poll},
+				ballots: map[string][]domain.Ballot{poll.ID: tt.ballots},
+			}
+			s := &RemindersUsecase{repo: repo, client: mm.start(t)}
+
+			sent, err := s.RemindDB(poll.ID, poll.CreatorID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := mm.recipients(); !slices.Equal(got, tt.want) || sent != len(tt.want) {
+				t.Errorf("reminded %v (sent %d), want %v", got, sent, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemindDBRejects(t *testing.T) {
+	mm := &fakeMattermost{members: map[string][]string{"town-square": {"u1"}}}
+	repo := &fakePolls{polls: map[string]domain.Poll{
+		"active": {ID: "active", CreatorID: "creator", Status: domain.StatusActive, ChannelID: "town-square"},
+		"closed": {ID: "closed", CreatorID: "creator", Status: domain.StatusClosed, ChannelID: "town-square"},
+	}}
+	s := &RemindersUsecase{repo: repo, client: mm.start(t)}
+
+	if _, err := s.RemindDB("active", "someone"); err == nil {
+		t.Error("reminder from a non-creator accepted")
+	}
+	if _, err := s.RemindDB("closed", "creator"); err == nil {
+		t.Error("reminder for a closed poll accepted")
+	}
+	if got := mm.recipients(); len(got) > 0 {
+		t.Errorf("reminded %v, want nobody", got)
+	}
+}
+
+func TestRemindDueDB(t *testing.T) {
+	mm := &fakeMattermost{members: map[string][]string{
+		"town-square": {botID, "u1", "u2"},
+		"random":      {"u3"},
+	}}
+	due := []domain.Poll{
+		{ID: "first", ChannelID: "town-square"},
+		{ID: "second", ChannelID: "random"},
+	}
+	repo := &fakePolls{
+		due:     due,
+		ballots: map[string][]domain.Ballot{"first": {{UserID: "u1"}}},
+	}
+	s := &RemindersUsecase{repo: repo, client: mm.start(t)}
+
+	polls, err := s.RemindDueDB(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(polls) != len(due) {
+		t.Errorf("got %d polls, want %d", len(polls), len(due))
+	}
+	if !slices.Equal(repo.reminded, []string{"first", "second"}) {
+		t.Errorf("marked reminded %v, want [first second]", repo.reminded)
+	}
+	if got := mm.recipients(); !slices.Equal(got, []string{"u2", "u3"}) {
+		t.Errorf("reminded %v, want [u2 u3]", got)
+	}
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	"github.com/bllooop/votingbot/internal/repository"
+)
+
+type TemplatesUsecase struct {
+	repo repository.Templates
+}
+
+func NewTemplatesUsecase(repo *repository.Repository) *TemplatesUsecase {
+	return &TemplatesUsecase{
+		repo: repo,
+	}
+}
+func (s *TemplatesUsecase) SaveTemplateDB(template domain.Template) error {
+	if template.Name == "" || strings.ContainsAny(template.Name, " \t") {
+		return fmt.Errorf("название шаблона должно быть одним словом")
+	}
+	if builtinTemplate(template.Name) != nil {
+		return fmt.Errorf("шаблон %s встроенный, выберите другое название", template.Name)
+	}
+	if len(template.Options) < 2 {
+		return fmt.Errorf("в шаблоне нужно указать хотя бы два варианта ответа")
+	}
+	settings := domain.Poll{
+		Options:    template.Options,
+		Kind:       template.Kind,
+		MaxChoices: template.MaxChoices,
+		Quorum:     template.Quorum,
+		Threshold:  template.Threshold,
+		TieBreak:   template.TieBreak,
+		WriteIn:    template.WriteIn,
+	}
+	if err := domain.CheckSettings(settings); err != nil {
+		return err
+	}
+	return s.repo.SaveTemplateDB(template)
+}
+func (s *TemplatesUsecase) DeleteTemplateDB(ownerID string, name string) error {
+	if builtinTemplate(name) != nil {
+		return fmt.Errorf("встроенный шаблон %s удалить нельзя", name)
+	}
+	return s.repo.DeleteTemplateDB(ownerID, name)
+}
+
+// GetTemplateDB ищет шаблон сначала среди встроенных, затем среди шаблонов
+// пользователя.
+func (s *TemplatesUsecase) GetTemplateDB(ownerID string, name string) (domain.Template, error) {
+	if template := builtinTemplate(name); template != nil {
+		return *template, nil
+	}
+	return s.repo.GetTemplateDB(ownerID, name)
+}
+
+// ListTemplatesDB возвращает встроенные шаблоны и шаблоны пользователя.
+func (s *TemplatesUsecase) ListTemplatesDB(ownerID string) ([]domain.Template, error) {
+	templates, err := s.repo.ListTemplatesDB(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return append(slices.Clone(domain.BuiltinTemplates), templates...), nil
+}
+
+func builtinTemplate(name string) *domain.Template {
+	idx := slices.IndexFunc(domain.BuiltinTemplates, func(template domain.Template) bool {
+		return template.Name == name
+	})
+	if idx < 0 {
+		return nil
+	}
+	return &domain.BuiltinTemplates[idx]
+}
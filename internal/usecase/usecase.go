@@ -1,23 +1,61 @@
 package usecase
 
 import (
+	"time"
+
 	"github.com/bllooop/votingbot/internal/domain"
+	"github.com/bllooop/votingbot/internal/mattermost"
 	"github.com/bllooop/votingbot/internal/repository"
 )
 
 type Polls interface {
-	CreateDB(question string, options []string, creatorId string) (string, []string, error)
-	CastDB(pollID string, option string) error
-	GetRes(pollID string) (domain.Results, error)
+	CreateDB(poll domain.Poll) (string, []string, error)
+	CastDB(ballot domain.Ballot) ([]string, error)
+	RevoteDB(ballot domain.Ballot) ([]string, error)
+	RetractDB(pollID string, userID string) error
+	GetRes(pollID string, userID string, peek bool) (domain.PollResults, error)
+	VotersDB(pollID string) (domain.Poll, []domain.Ballot, error)
 	CloseDB(pollID string, creatorId string) error
+	CloseExpiredDB(now time.Time) ([]domain.Poll, error)
+	PublishDB(pollID string, creatorId string) (domain.Poll, error)
+	ReopenDB(pollID string, creatorId string, expiresAt int64) (domain.Poll, error)
+	ArchiveDB(pollID string, creatorId string) error
+	ApproveDB(pollID string, creatorId string, option string) error
+	EditDB(pollID string, creatorId string, edit domain.PollEdit) error
+	TieBreakDB(pollID string, creatorId string, option string) error
+	OpenScheduledDB(now time.Time) ([]domain.Poll, error)
+	ListDB(channelID string, userID string, filter string, page int) (domain.PollPage, error)
+	MineDB(userID string, page int) (domain.PollPage, error)
+	SearchDB(query string, channelID string, teamID string, userID string, page int) (domain.PollPage, error)
 	DeleteDB(pollID string, creatorId string) error
 }
+type Weights interface {
+	SetWeightDB(weight domain.Weight, adminID string) (int, error)
+	RemoveWeightDB(scope string, userID string, adminID string) (int, error)
+	ListWeightsDB(scope string) ([]domain.Weight, error)
+}
+type Reminders interface {
+	RemindDB(pollID string, creatorId string) (int, error)
+	RemindDueDB(now time.Time) ([]domain.Poll, error)
+}
+type Templates interface {
+	SaveTemplateDB(template domain.Template) error
+	DeleteTemplateDB(ownerID string, name string) error
+	GetTemplateDB(ownerID string, name string) (domain.Template, error)
+	ListTemplatesDB(ownerID string) ([]domain.Template, error)
+}
 type Usecase struct {
 	Polls
+	Weights
+	Reminders
+	Templates
 }
 
-func NewUsecase(repo *repository.Repository) *Usecase {
+func NewUsecase(repo *repository.Repository, admins []string, client mattermost.API) *Usecase {
 	return &Usecase{
-		Polls: NewPollsUsecase(repo),
+		Polls:     NewPollsUsecase(repo, client),
+		Weights:   NewWeightsUsecase(repo, admins, client),
+		Reminders: NewRemindersUsecase(repo, client),
+		Templates: NewTemplatesUsecase(repo),
 	}
 }
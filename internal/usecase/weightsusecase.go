@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bllooop/votingbot/internal/domain"
+	"github.com/bllooop/votingbot/internal/mattermost"
+	"github.com/bllooop/votingbot/internal/repository"
+)
+
+type WeightsUsecase struct {
+	repo   repository.Weights
+	polls  repository.Polls
+	admins []string
+	client mattermost.API
+}
+
+func NewWeightsUsecase(repo *repository.Repository, admins []string, client mattermost.API) *WeightsUsecase {
+	return &WeightsUsecase{
+		repo:   repo,
+		polls:  repo,
+		admins: admins,
+		client: client,
+	}
+}
+
+// SetWeightDB назначает вес голоса пользователю. Вместо ID можно указать
+// @пользователя или @группу: вес получает каждый участник группы на момент
+// назначения. Возвращает число пользователей, получивших вес.
+func (s *WeightsUsecase) SetWeightDB(weight domain.Weight, adminID string) (int, error) {
+	if err := s.checkAdmin(adminID); err != nil {
+		return 0, err
+	}
+	if weight.Weight < domain.MinWeight || weight.Weight > domain.MaxWeight {
+		return 0, fmt.Errorf("вес голоса должен быть от %d до %d", domain.MinWeight, domain.MaxWeight)
+	}
+	scope, err := s.resolveScope(weight.Scope)
+	if err != nil {
+		return 0, err
+	}
+	userIDs, err := s.resolveUsers(weight.UserID)
+	if err != nil {
+		return 0, err
+	}
+	weight.Scope = scope
+	for _, userID := range userIDs {
+		weight.UserID = userID
+		if err := s.repo.SetWeightDB(weight); err != nil {
+			return 0, err
+		}
+	}
+	return len(userIDs), nil
+}
+func (s *WeightsUsecase) RemoveWeightDB(scope string, userID string, adminID string) (int, error) {
+	if err := s.checkAdmin(adminID); err != nil {
+		return 0, err
+	}
+	scope, err := s.resolveScope(scope)
+	if err != nil {
+		return 0, err
+	}
+	userIDs, err := s.resolveUsers(userID)
+	if err != nil {
+		return 0, err
+	}
+	for _, userID := range userIDs {
+		if err := s.repo.RemoveWeightDB(scope, userID); err != nil {
+			return 0, err
+		}
+	}
+	return len(userIDs), nil
+}
+func (s *WeightsUsecase) ListWeightsDB(scope string) ([]domain.Weight, error) {
+	scope, err := s.resolveScope(scope)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListWeightsDB(scope)
+}
+
+// resolveScope заменяет короткий ID голосования в области действия веса
+// полным, под которым веса хранятся.
+func (s *WeightsUsecase) resolveScope(scope string) (string, error) {
+	pollID, ok := strings.CutPrefix(scope, domain.PollScope(""))
+	if !ok || !domain.IsShortID(pollID) {
+		return scope, nil
+	}
+	poll, err := s.polls.GetPollDB(pollID)
+	if err != nil {
+		return "", err
+	}
+	return domain.PollScope(poll.ID), nil
+}
+
+// resolveUsers превращает @пользователя или @группу в ID пользователей.
+// Значение без @ считается ID пользователя.
+func (s *WeightsUsecase) resolveUsers(target string) ([]string, error) {
+	name, ok := strings.CutPrefix(target, "@")
+	if !ok {
+		return []string{target}, nil
+	}
+	userIDs, err := resolveMentions(s.client, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	if len(userIDs) == 0 {
+		return nil, fmt.Errorf("в группе %s нет участников", name)
+	}
+	return userIDs, nil
+}
+
+func (s *WeightsUsecase) checkAdmin(userID string) error {
+	if !slices.Contains(s.admins, userID) {
+		return fmt.Errorf("%w: управлять весами голосов могут только администраторы", domain.ErrForbidden)
+	}
+	return nil
+}